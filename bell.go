@@ -0,0 +1,53 @@
+package tcellterm
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// VisualBell flashes the screen by drawing it in reverse video for d. An
+// EventRedraw is delivered when the flash starts and when it ends. A bell rung
+// during a flash extends it
+func (vt *VT) VisualBell(d time.Duration) {
+	vt.mu.Lock()
+	vt.flashGen += 1
+	gen := vt.flashGen
+	if !vt.flash {
+		vt.flash = true
+		vt.damageAll()
+	}
+	vt.mu.Unlock()
+	vt.redrawNow()
+
+	time.AfterFunc(d, func() {
+		vt.mu.Lock()
+		if gen != vt.flashGen {
+			vt.mu.Unlock()
+			return
+		}
+		vt.flash = false
+		vt.damageAll()
+		vt.mu.Unlock()
+		vt.redrawNow()
+	})
+}
+
+// reverseVideo reports if the screen is drawn in reverse video, with DECSCNM
+// or during a visual bell. A visual bell in DECSCNM draws the screen normally
+func (vt *VT) reverseVideo() bool {
+	return (vt.mode&decscnm != 0) != vt.flash
+}
+
+// swapDefaultColors returns style with the default foreground and background
+// colors swapped, as in reverse video screen mode. tcell can't name the default
+// colors, so the colors of the style trade places and the reverse attribute is
+// toggled: explicit colors are drawn in the same role as before, and the
+// default colors are drawn swapped
+func swapDefaultColors(style tcell.Style) tcell.Style {
+	fg, bg, attrs := style.Decompose()
+	return style.
+		Foreground(bg).
+		Background(fg).
+		Reverse(attrs&tcell.AttrReverse == 0)
+}
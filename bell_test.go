@@ -0,0 +1,57 @@
+package tcellterm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisualBell(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 1)
+	srf := &frame{w: 2, h: 1}
+	vt.SetSurface(srf)
+	redraws := make(chan struct{}, 2)
+	vt.Attach(func(ev tcell.Event) {
+		if _, ok := ev.(*EventRedraw); ok {
+			redraws <- struct{}{}
+		}
+	})
+	_, _ = vt.Write([]byte("a"))
+	<-redraws
+	vt.Draw()
+
+	reversed := func() bool {
+		srf.cells = nil
+		vt.Draw()
+		if !assert.Len(t, srf.cells, 2) {
+			return false
+		}
+		_, _, attrs := srf.cells[0].style.Decompose()
+		return attrs&tcell.AttrReverse != 0
+	}
+
+	vt.VisualBell(20 * time.Millisecond)
+	<-redraws
+	assert.True(t, reversed())
+
+	select {
+	case <-redraws:
+	case <-time.After(time.Second):
+		t.Fatal("no redraw at the end of the bell")
+	}
+	assert.False(t, reversed())
+}
+
+func TestVisualBellReverseVideo(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 1)
+	_, _ = vt.Write([]byte("\x1b[?5h"))
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	assert.True(t, vt.reverseVideo())
+	vt.flash = true
+	assert.False(t, vt.reverseVideo())
+}
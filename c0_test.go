@@ -49,6 +49,98 @@ func TestLF(t *testing.T) {
 	})
 }
 
+func TestNewLineMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		screen string
+		row    int
+		col    int
+	}{
+		{
+			name:   "LF",
+			input:  "ab\ncd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "VT",
+			input:  "ab\vcd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "FF",
+			input:  "ab\fcd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "LF new line",
+			input:  "\x1b[20hab\ncd",
+			screen: "ab  \ncd  ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "VT new line",
+			input:  "\x1b[20hab\vcd",
+			screen: "ab  \ncd  ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "FF new line",
+			input:  "\x1b[20hab\fcd",
+			screen: "ab  \ncd  ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "new line reset",
+			input:  "\x1b[20h\x1b[20lab\ncd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "new line soft reset",
+			input:  "\x1b[20h\x1b[!pab\ncd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "new line hard reset",
+			input:  "\x1b[20h\x1bcab\ncd",
+			screen: "ab  \n  cd",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "new line at the bottom scrolls",
+			input:  "\x1b[20hab\ncd\nef",
+			screen: "cd  \nef  ",
+			row:    1,
+			col:    2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 2)
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.screen, vt.String())
+			row, col, _, _ := vt.Cursor()
+			assert.Equal(t, test.row, row, "row")
+			assert.Equal(t, test.col, col, "col")
+		})
+	}
+}
+
 // // Linefeed 0x10
 // func (vt *vt) LF() {
 // 	switch {
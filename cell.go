@@ -7,9 +7,84 @@ type cell struct {
 	combining []rune
 	width     int
 	attrs     tcell.Style
+	ext       extAttrs
 	wrapped   bool
 }
 
+// UnderlineStyle is the style of the underline of a cell
+type UnderlineStyle int
+
+const (
+	UnderlineNone UnderlineStyle = iota
+	UnderlineSingle
+	UnderlineDouble
+	UnderlineCurly
+	UnderlineDotted
+	UnderlineDashed
+)
+
+// ExtAttrMask is a set of the attributes of a cell which a tcell.Style can't
+// hold
+type ExtAttrMask uint16
+
+const (
+	// ExtAttrRapidBlink is set for rapidly blinking text. tcell draws it as
+	// blinking text
+	ExtAttrRapidBlink ExtAttrMask = 1 << iota
+	// ExtAttrConceal is set for concealed text, which is drawn blank
+	ExtAttrConceal
+	ExtAttrOverline
+	ExtAttrFramed
+	ExtAttrEncircled
+	ExtAttrSuperscript
+	ExtAttrSubscript
+)
+
+// extAttrs are the attributes of a cell which a tcell.Style can't hold. The
+// underline attribute of the tcell.Style is kept set while the underline style
+// is not UnderlineNone, so tcell draws any style as a plain underline
+type extAttrs struct {
+	underline      UnderlineStyle
+	underlineColor tcell.Color
+	attrs          ExtAttrMask
+	// font is the alternate font, 1 through 9, or 0 for the primary font
+	font int
+	// link is the OSC 8 hyperlink of the cell. It is added to the
+	// tcell.Style when the cell is drawn
+	link hyperlink
+}
+
+// hyperlink is the target of an OSC 8 hyperlink, and its optional id
+type hyperlink struct {
+	url string
+	id  string
+}
+
+// Cell is the content and attributes of a cell of the screen
+type Cell struct {
+	// Content is the character of the cell, or 0 if the cell is empty
+	Content rune
+	// Combining are the combining characters following Content
+	Combining []rune
+	// Width is the number of columns of Content. Cells covered by a wide
+	// character have a width of 0
+	Width int
+	// Style is the stored style of the cell, including its hyperlink.
+	// Selection, search highlights, reverse video and concealment are
+	// applied by Draw and are not included
+	Style tcell.Style
+	// Underline is the style of the underline
+	Underline UnderlineStyle
+	// UnderlineColor is the color of the underline. The foreground color is
+	// used when it is tcell.ColorDefault
+	UnderlineColor tcell.Color
+	// ExtAttrs are the attributes which Style can't hold
+	ExtAttrs ExtAttrMask
+	// Font is the alternate font selected with SGR 11 through 19, 1
+	// through 9, or 0 for the primary font
+	Font int
+}
+
 func (c *cell) rune() rune {
 	if c.content == rune(0) {
 		return ' '
@@ -17,6 +92,19 @@ func (c *cell) rune() rune {
 	return c.content
 }
 
+// style returns the style the cell is drawn with, including its hyperlink
+func (c *cell) style() tcell.Style {
+	style := c.attrs
+	if c.ext.link.url == "" {
+		return style
+	}
+	style = style.Url(c.ext.link.url)
+	if c.ext.link.id != "" {
+		style = style.UrlId(c.ext.link.id)
+	}
+	return style
+}
+
 // Erasing removes characters from the screen without affecting other characters
 // on the screen. Erased characters are lost. The cursor position does not
 // change when erasing characters or lines. Erasing resets the attributes, but
@@ -25,9 +113,40 @@ func (c *cell) erase(s tcell.Style) {
 	_, bg, _ := s.Decompose()
 	c.content = 0
 	c.attrs = tcell.StyleDefault.Background(bg)
+	c.ext = extAttrs{}
 }
 
 // selectiveErase removes the cell content, but keeps the attributes
 func (c *cell) selectiveErase() {
 	c.content = 0
 }
+
+// CellAt returns the cell at column x and row y of the view, with its stored
+// style. Positions outside of the view return an empty Cell
+func (vt *VT) CellAt(x int, y int) Cell {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if y < 0 || y >= vt.height() {
+		return Cell{}
+	}
+	line := vt.viewRow(y)
+	if x < 0 || x >= len(line) {
+		return Cell{}
+	}
+	c := line[x]
+	var combining []rune
+	if len(c.combining) > 0 {
+		combining = make([]rune, len(c.combining))
+		copy(combining, c.combining)
+	}
+	return Cell{
+		Content:        c.content,
+		Combining:      combining,
+		Width:          c.width,
+		Style:          c.style(),
+		Underline:      c.ext.underline,
+		UnderlineColor: c.ext.underlineColor,
+		ExtAttrs:       c.ext.attrs,
+		Font:           c.ext.font,
+	}
+}
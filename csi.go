@@ -78,7 +78,7 @@ func (vt *VT) csi(csi string, params []int) {
 		resp.WriteString("22")
 		// Response terminator
 		resp.WriteString("c")
-		vt.pty.WriteString(resp.String())
+		vt.send(resp.String())
 	case "d":
 		vt.vpa(ps(params))
 	case "e":
@@ -96,27 +96,51 @@ func (vt *VT) csi(csi string, params []int) {
 		vt.rm(params)
 	case "?l":
 		vt.decrst(params)
+	case "$p":
+		vt.decrqm(ps(params), false)
+	case "?$p":
+		vt.decrqm(ps(params), true)
+	case "!p":
+		vt.decstr()
 	case "m":
 		vt.sgr(params)
+	case ">m":
+		vt.xtmodkeys(params)
+	case "?m":
+		vt.xtqmodkeys(params)
+	case ">n":
+		vt.xtdismodkeys(params)
 	case "n":
 		// Send device status report
 		switch ps(params) {
 		case 5:
 			// "Ok"
-			vt.pty.WriteString("\x1B[0n")
+			vt.send("\x1B[0n")
 		case 6:
 			// report cursor position
 			// This sequence can be identical to a function key?
 			// CSI r ; c R
 			resp := fmt.Sprintf("\x1B[%d;%dR", vt.cursor.row+1, vt.cursor.col+1)
-			vt.pty.WriteString(resp)
+			vt.send(resp)
 		}
 	case "r":
 		vt.decstbm(params)
 	case "s":
+		if vt.mode&declrmm != 0 {
+			vt.decslrm(params)
+			return
+		}
 		vt.decsc()
 	case "u":
 		vt.decrc()
+	case ">u":
+		vt.pushKeyboardFlags(ps(params))
+	case "<u":
+		vt.popKeyboardFlags(ps(params))
+	case "=u":
+		vt.setKeyboardFlags(params)
+	case "?u":
+		vt.queryKeyboardFlags()
 	case " q":
 		ps(params)
 		vt.cursor.style = tcell.CursorStyle(ps(params))
@@ -141,18 +165,16 @@ func (vt *VT) ich(ps int) {
 	}
 	col := vt.cursor.col
 	row := vt.cursor.row
+	if col < vt.margin.left || col > vt.margin.right {
+		return
+	}
 	line := vt.activeScreen[row]
-	for i := vt.margin.right; i > col; i -= 1 {
-		if col+i > column(vt.width()-1) {
-			break
-		}
-		if (i - column(ps)) < 0 {
-			continue
-		}
+	vt.damage(row)
+	for i := vt.margin.right; i >= col+column(ps); i -= 1 {
 		line[i] = line[i-column(ps)]
 	}
 	for i := 0; i < ps; i += 1 {
-		if int(col)+i >= (vt.width() - 1) {
+		if col+column(i) > vt.margin.right {
 			break
 		}
 		line[col+column(i)] = cell{
@@ -244,24 +266,16 @@ func (vt *VT) cpl(ps int) {
 }
 
 // Cursor Character Absolute (CHA) CSI Ps G
-// Move cursor to Ps column, stopping at right/left margin. Default is 1, but we
-// default to 0 since our columns our 0 indexed
+// Move cursor to Ps column. In origin mode, the column is relative to the left
+// margin, stopping at the right margin
 func (vt *VT) cha(ps int) {
 	vt.lastCol = false
-	if ps == 0 {
-		ps = 1
-	}
-	vt.cursor.col = column(ps - 1)
-	if vt.cursor.col > vt.margin.right {
-		vt.cursor.col = vt.margin.right
-	}
-	if vt.cursor.col < vt.margin.left {
-		vt.cursor.col = vt.margin.left
-	}
+	vt.cursor.col = vt.originCol(ps)
 }
 
 // Cursor Position (CUP) CSI Ps;Ps H
-// Move cursor to the absolute position
+// Move cursor to the absolute position. In origin mode, the position is
+// relative to the margins
 func (vt *VT) cup(pm []int) {
 	vt.lastCol = false
 	switch len(pm) {
@@ -273,14 +287,8 @@ func (vt *VT) cup(pm []int) {
 	default:
 		return
 	}
-	vt.cursor.row = row(pm[0] - 1)
-	vt.cursor.col = column(pm[1] - 1)
-	if vt.cursor.col > column(vt.width()-1) {
-		vt.cursor.col = column(vt.width() - 1)
-	}
-	if vt.cursor.row > row(vt.height()-1) {
-		vt.cursor.row = row(vt.height() - 1)
-	}
+	vt.cursor.row = vt.originRow(pm[0])
+	vt.cursor.col = vt.originCol(pm[1])
 }
 
 // Cursor Forward Tabulation (CHT) CSI Ps I
@@ -312,6 +320,7 @@ func (vt *VT) ed(ps int) {
 	// completely erased lines.
 	case 0:
 		vt.lastCol = false
+		vt.damageRows(vt.cursor.row, row(vt.height())-1)
 		for r := vt.cursor.row; r < row(vt.height()); r += 1 {
 			for col := column(0); col < column(vt.width()); col += 1 {
 				if r == vt.cursor.row && col < vt.cursor.col {
@@ -327,6 +336,7 @@ func (vt *VT) ed(ps int) {
 	// for all completely erased lines.
 	case 1:
 		vt.lastCol = false
+		vt.damageRows(0, vt.cursor.row)
 		for r := row(0); r <= vt.cursor.row; r += 1 {
 			for col := column(0); col < column(vt.width()); col += 1 {
 				if r == vt.cursor.row && col > vt.cursor.col {
@@ -342,11 +352,16 @@ func (vt *VT) ed(ps int) {
 	// single-width. The cursor does not move.
 	case 2:
 		vt.lastCol = false
+		vt.damageRows(0, row(vt.height())-1)
 		for r := row(0); r < row(vt.height()); r += 1 {
 			for col := column(0); col < column(vt.width()); col += 1 {
 				vt.activeScreen[r][col].erase(vt.cursor.attrs)
 			}
 		}
+
+	// Erases the scrollback history. The screen is not affected.
+	case 3:
+		vt.clearHistory()
 	}
 }
 
@@ -354,6 +369,7 @@ func (vt *VT) ed(ps int) {
 func (vt *VT) el(ps int) {
 	r := vt.cursor.row
 	vt.lastCol = false
+	vt.damage(r)
 	switch ps {
 	// Erases from the cursor to the end of the line, including the cursor
 	// position. Line attribute is not affected.
@@ -408,9 +424,13 @@ func (vt *VT) il(ps int) {
 		ps = int(vt.margin.bottom - vt.cursor.row)
 	}
 
+	vt.scrollSelection(int(vt.cursor.row), int(vt.margin.bottom), -ps, false)
+	vt.scrollHighlights(int(vt.cursor.row), int(vt.margin.bottom), -ps, false)
+	vt.damageRows(vt.cursor.row, vt.margin.bottom)
+
 	// move the lines first
 	for r := vt.margin.bottom; r >= (vt.cursor.row + row(ps)); r -= 1 {
-		copy(vt.activeScreen[r], vt.activeScreen[r-row(ps)])
+		vt.copyRow(r, r-row(ps))
 	}
 
 	// insert the blank lines (we do this by erasing the cells)
@@ -453,9 +473,13 @@ func (vt *VT) dl(ps int) {
 		ps = int(vt.margin.bottom - vt.cursor.row)
 	}
 
+	vt.scrollSelection(int(vt.cursor.row), int(vt.margin.bottom), ps, false)
+	vt.scrollHighlights(int(vt.cursor.row), int(vt.margin.bottom), ps, false)
+	vt.damageRows(vt.cursor.row, vt.margin.bottom)
+
 	for r := vt.cursor.row; r <= vt.margin.bottom; r += 1 {
 		if r <= vt.margin.bottom-row(ps) {
-			copy(vt.activeScreen[r], vt.activeScreen[r+row(ps)])
+			vt.copyRow(r, r+row(ps))
 			continue
 		}
 		for col := vt.margin.left; col <= vt.margin.right; col += 1 {
@@ -478,6 +502,10 @@ func (vt *VT) dch(ps int) {
 		ps = 1
 	}
 	row := vt.cursor.row
+	if vt.cursor.col < vt.margin.left || vt.cursor.col > vt.margin.right {
+		return
+	}
+	vt.damage(row)
 	for col := vt.cursor.col; col <= vt.margin.right; col += 1 {
 		if col+column(ps) > vt.margin.right {
 			vt.activeScreen[row][col].erase(vt.cursor.attrs)
@@ -499,6 +527,7 @@ func (vt *VT) ech(ps int) {
 		ps = 1
 	}
 
+	vt.damage(vt.cursor.row)
 	for i := column(0); i < column(ps); i += 1 {
 		if vt.cursor.col+i == column(vt.width())-1 {
 			return
@@ -547,16 +576,11 @@ func (vt *VT) tbc(ps int) {
 
 // Line Position Absolute (VPA) CSI Ps d
 //
-// Move cursor to line Ps
+// Move cursor to line Ps. In origin mode, the line is relative to the top
+// margin
 func (vt *VT) vpa(ps int) {
 	vt.lastCol = false
-	if ps == 0 {
-		ps = 1
-	}
-	vt.cursor.row = row(ps - 1)
-	if vt.cursor.row > row(vt.height()-1) {
-		vt.cursor.row = row(vt.height() - 1)
-	}
+	vt.cursor.row = vt.originRow(ps)
 }
 
 // Line Position Relative (VPR) CSI Ps e
@@ -575,16 +599,11 @@ func (vt *VT) vpr(ps int) {
 
 // Character Position Absolute (HPA) CSI Ps `
 //
-// Move cursor to column Ps
+// Move cursor to column Ps. In origin mode, the column is relative to the left
+// margin
 func (vt *VT) hpa(ps int) {
 	vt.lastCol = false
-	if ps == 0 {
-		ps = 1
-	}
-	vt.cursor.col = column(ps - 1)
-	if vt.cursor.col > column(vt.width()-1) {
-		vt.cursor.col = column(vt.width() - 1)
-	}
+	vt.cursor.col = vt.originCol(ps)
 }
 
 // Character Position Relative (HPR) CSI Ps a
@@ -611,6 +630,7 @@ func (vt *VT) rep(ps int) {
 		return
 	}
 	ch := vt.activeScreen[vt.cursor.row][col-1]
+	vt.damage(vt.cursor.row)
 	for i := 0; i < ps; i += 1 {
 		if col + column(i) == vt.margin.right {
 			return
@@ -622,13 +642,128 @@ func (vt *VT) rep(ps int) {
 // Set top and bottom margins CSI Ps ; Ps r
 func (vt *VT) decstbm(pm []int) {
 	vt.lastCol = false
-	if len(pm) != 2 {
-		vt.margin.top = 0
-		vt.margin.bottom = row(vt.height()) - 1
+	top, bottom := 1, vt.height()
+	if len(pm) > 0 && pm[0] > 0 {
+		top = pm[0]
+	}
+	if len(pm) > 1 && pm[1] > 0 && pm[1] < bottom {
+		bottom = pm[1]
+	}
+	if top >= bottom {
 		return
 	}
-	vt.margin.top = row(pm[0]) - 1
-	vt.margin.bottom = row(pm[1]) - 1
-	vt.cursor.row = 0
-	vt.cursor.col = 0
+	vt.margin.top = row(top - 1)
+	vt.margin.bottom = row(bottom - 1)
+	vt.home()
+}
+
+// Set left and right margins (DECSLRM) CSI Pl ; Pr s
+//
+// Sets the left and right margins. Only available when left and right margin
+// mode (DECLRMM) is set, otherwise CSI s saves the cursor
+func (vt *VT) decslrm(pm []int) {
+	vt.lastCol = false
+	left, right := 1, vt.width()
+	if len(pm) > 0 && pm[0] > 0 {
+		left = pm[0]
+	}
+	if len(pm) > 1 && pm[1] > 0 && pm[1] < right {
+		right = pm[1]
+	}
+	if left >= right {
+		return
+	}
+	vt.margin.left = column(left - 1)
+	vt.margin.right = column(right - 1)
+	vt.home()
+}
+
+// fullWidth reports if the left and right margins are at the edges of the
+// screen
+func (vt *VT) fullWidth() bool {
+	return vt.margin.left == 0 && vt.margin.right == column(vt.width()-1)
+}
+
+// home moves the cursor to the home position: the top left of the screen, or
+// of the margins in origin mode
+func (vt *VT) home() {
+	vt.lastCol = false
+	vt.cursor.row = vt.originRow(1)
+	vt.cursor.col = vt.originCol(1)
+}
+
+// originRow converts a 1-based line parameter to a row of the screen. In origin
+// mode, lines are relative to the top margin and limited to the scrolling
+// region
+func (vt *VT) originRow(ps int) row {
+	if ps < 1 {
+		ps = 1
+	}
+	top, bottom := row(0), row(vt.height()-1)
+	if vt.mode&decom != 0 {
+		top, bottom = vt.margin.top, vt.margin.bottom
+	}
+	r := top + row(ps-1)
+	if r > bottom {
+		r = bottom
+	}
+	return r
+}
+
+// originCol converts a 1-based column parameter to a column of the screen. In
+// origin mode, columns are relative to the left margin and limited to the
+// margins
+func (vt *VT) originCol(ps int) column {
+	if ps < 1 {
+		ps = 1
+	}
+	left, right := column(0), column(vt.width()-1)
+	if vt.mode&decom != 0 {
+		left, right = vt.margin.left, vt.margin.right
+	}
+	col := left + column(ps-1)
+	if col > right {
+		col = right
+	}
+	return col
+}
+
+// Soft terminal reset (DECSTR) CSI ! p
+//
+// Resets modes, margins, character sets and graphic rendition to their
+// defaults. Unlike RIS, the screen and the cursor position are kept
+func (vt *VT) decstr() {
+	vt.mode &^= kam | irm | lnm | decckm | deckpam | decom | declrmm
+	vt.mode |= dectcem
+	vt.lastCol = false
+	vt.margin = margin{
+		top:    0,
+		bottom: row(vt.height()) - 1,
+		left:   0,
+		right:  column(vt.width()) - 1,
+	}
+	vt.cursor.attrs = tcell.StyleDefault
+	vt.cursor.ext = extAttrs{}
+	vt.charsets = charsets{
+		designations: map[charsetDesignator]charset{
+			g0: ascii,
+			g1: ascii,
+			g2: ascii,
+			g3: ascii,
+		},
+	}
+	// The saved cursors are moved home
+	for _, state := range []*cursorState{&vt.primaryState, &vt.altState} {
+		*state = cursorState{
+			charsets: charsets{
+				designations: map[charsetDesignator]charset{
+					g0: ascii,
+					g1: ascii,
+					g2: ascii,
+					g3: ascii,
+				},
+			},
+			decawm: vt.mode&decawm != 0,
+		}
+	}
 }
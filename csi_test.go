@@ -3,6 +3,7 @@ package tcellterm
 import (
 	"testing"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -103,3 +104,162 @@ func TestDCH(t *testing.T) {
 	vt.dch(2)
 	assert.Equal(t, "ad  ", vt.String())
 }
+
+func TestMargins(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		screen string
+		row    int
+		col    int
+	}{
+		{
+			name:   "CSI s saves cursor without DECLRMM",
+			input:  "\x1b[2;3Hx\x1b[2;3s\x1b[Hy\x1b[uz",
+			screen: "y   \n  xz\n    ",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "DECSLRM homes cursor",
+			input:  "\x1b[?69h\x1b[3;3H\x1b[2;3s",
+			screen: "    \n    \n    ",
+			row:    0,
+			col:    0,
+		},
+		{
+			name:   "DECSLRM ignores empty margins",
+			input:  "\x1b[?69h\x1b[3;2s\x1b[2;2Habcde",
+			screen: "    \n abc\nde  ",
+			row:    2,
+			col:    2,
+		},
+		{
+			name:   "scroll within margins",
+			input:  "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[S",
+			screen: "afgd\nejkh\ni  l",
+			row:    0,
+			col:    0,
+		},
+		{
+			name:   "scroll down within margins",
+			input:  "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[T",
+			screen: "a  d\nebch\nifgl",
+			row:    0,
+			col:    0,
+		},
+		{
+			name:   "IL within margins",
+			input:  "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[2;2H\x1b[L",
+			screen: "abcd\ne  h\nifgl",
+			row:    1,
+			col:    1,
+		},
+		{
+			name:   "IL outside margins",
+			input:  "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[2;4H\x1b[L",
+			screen: "abcd\nefgh\nijkl",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "DL within margins",
+			input:  "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[2;2H\x1b[M",
+			screen: "abcd\nejkh\ni  l",
+			row:    1,
+			col:    1,
+		},
+		{
+			name:   "ICH within margins",
+			input:  "abcd\x1b[?69h\x1b[1;3s\x1b[1;2H\x1b[@",
+			screen: "a bd\n    \n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "DCH within margins",
+			input:  "abcd\x1b[?69h\x1b[1;3s\x1b[1;2H\x1b[P",
+			screen: "ac d\n    \n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "DECLRMM reset restores margins",
+			input:  "\x1b[?69h\x1b[2;3s\x1b[?69l\x1b[Habcd",
+			screen: "abcd\n    \n    ",
+			row:    0,
+			col:    3,
+		},
+		{
+			name:   "origin mode CUP",
+			input:  "\x1b[?69h\x1b[2;3s\x1b[2;3r\x1b[?6h\x1b[1;1Hx\x1b[9;9Hy",
+			screen: "    \n x  \n  y ",
+			row:    2,
+			col:    2,
+		},
+		{
+			name:   "origin mode VPA and HPA",
+			input:  "\x1b[?69h\x1b[2;3s\x1b[2;3r\x1b[?6h\x1b[2dx\x1b[2`y",
+			screen: "    \n    \n xy ",
+			row:    2,
+			col:    2,
+		},
+		{
+			name:   "origin mode CHA",
+			input:  "\x1b[?69h\x1b[2;3s\x1b[?6h\x1b[2Gx",
+			screen: "  x \n    \n    ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "origin mode reset homes cursor",
+			input:  "\x1b[2;3r\x1b[?6h\x1b[2;2H\x1b[?6lx",
+			screen: "x   \n    \n    ",
+			row:    0,
+			col:    1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 3)
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.screen, vt.String())
+			row, col, _, _ := vt.Cursor()
+			assert.Equal(t, test.row, row, "row")
+			assert.Equal(t, test.col, col, "col")
+		})
+	}
+}
+
+func TestMarginsHistory(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 2)
+	_, _ = vt.Write([]byte("\x1b[?69h\x1b[1;3s\x1b[2;1Ha\n"))
+	assert.Empty(t, vt.history)
+	_, _ = vt.Write([]byte("\x1b[?69l\x1b[2;1Hb\n"))
+	assert.Len(t, vt.history, 1)
+}
+
+func TestDECSTR(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	input := "\x1b[2;3r\x1b[?69h\x1b[2;3s" +
+		"\x1b[4h\x1b[20h\x1b[?1h\x1b=\x1b[?6h\x1b[?25l" +
+		"\x1b[1;31m\x1b(0\x1b[2;2H\x1b7q\x1b[!p"
+	_, _ = vt.Write([]byte(input))
+
+	// The screen and cursor are kept
+	assert.Equal(t, "    \n    \n  ─ ", vt.String())
+	row, col, _, _ := vt.Cursor()
+	assert.Equal(t, 2, row)
+	assert.Equal(t, 2, col)
+
+	assert.Equal(t, decawm|dectcem, vt.mode)
+	assert.Equal(t, margin{top: 0, bottom: 2, left: 0, right: 3}, vt.margin)
+	assert.Equal(t, tcell.StyleDefault, vt.cursor.attrs)
+
+	// The saved cursor is home, and the character sets are reset
+	_, _ = vt.Write([]byte("\x1b8q"))
+	assert.Equal(t, "q   \n    \n  ─ ", vt.String())
+}
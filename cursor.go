@@ -6,6 +6,7 @@ import (
 
 type cursor struct {
 	attrs tcell.Style
+	ext   extAttrs
 	style tcell.CursorStyle
 
 	// position
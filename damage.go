@@ -0,0 +1,84 @@
+package tcellterm
+
+// Rect is a rectangle of cells on the Surface
+type Rect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// damage marks row r of the active screen as changed since the last Draw
+func (vt *VT) damage(r row) {
+	if r < 0 || int(r) >= len(vt.damaged) {
+		return
+	}
+	vt.damaged[r] = true
+}
+
+// damageRows marks rows top through bottom of the active screen as changed
+func (vt *VT) damageRows(top row, bottom row) {
+	for r := top; r <= bottom; r += 1 {
+		vt.damage(r)
+	}
+}
+
+// damageAll marks the entire view as changed. It is used whenever the view
+// changes as a whole: when it is resized, scrolled back, or switched to
+// another screen, and when the selection or highlights change
+func (vt *VT) damageAll() {
+	if len(vt.damaged) != vt.height() {
+		vt.damaged = make([]bool, vt.height())
+	}
+	vt.allDamaged = true
+	if vt.frozen != nil {
+		vt.frozen.damaged = true
+	}
+}
+
+// Invalidate marks the entire terminal to be repainted by the next Draw. Hosts
+// should call Invalidate when the contents of the Surface are lost, for
+// example after clearing the screen or moving the terminal to a new Surface
+func (vt *VT) Invalidate() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.damageAll()
+}
+
+// damagedRects returns the rows of the view which have changed since the last
+// Draw, as rectangles spanning the width of the view, and clears the damage
+func (vt *VT) damagedRects() []Rect {
+	var rects []Rect
+	for y := 0; y < vt.height(); y += 1 {
+		if !vt.rowDamaged(y) {
+			continue
+		}
+		if n := len(rects); n > 0 && rects[n-1].Y+rects[n-1].Height == y {
+			rects[n-1].Height += 1
+			continue
+		}
+		rects = append(rects, Rect{
+			X:      0,
+			Y:      y,
+			Width:  vt.width(),
+			Height: 1,
+		})
+	}
+	for i := range vt.damaged {
+		vt.damaged[i] = false
+	}
+	vt.allDamaged = false
+	return rects
+}
+
+// rowDamaged reports if row y of the view has changed since the last Draw
+func (vt *VT) rowDamaged(y int) bool {
+	if vt.allDamaged {
+		return true
+	}
+	r := y - vt.offset()
+	if r < 0 || r >= len(vt.damaged) {
+		return false
+	}
+	return vt.damaged[r]
+}
@@ -0,0 +1,153 @@
+package tcellterm
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSurface records which rows are painted
+type testSurface struct {
+	w     int
+	h     int
+	rows  map[int]bool
+	cells [][]rune
+}
+
+func newTestSurface(w int, h int) *testSurface {
+	cells := make([][]rune, h)
+	for i := range cells {
+		cells[i] = make([]rune, w)
+	}
+	return &testSurface{
+		w:     w,
+		h:     h,
+		rows:  map[int]bool{},
+		cells: cells,
+	}
+}
+
+func (s *testSurface) SetContent(x int, y int, ch rune, comb []rune, style tcell.Style) {
+	s.rows[y] = true
+	s.cells[y][x] = ch
+}
+
+func (s *testSurface) Size() (int, int) {
+	return s.w, s.h
+}
+
+// painted returns the rows painted since the last call
+func (s *testSurface) painted() []int {
+	rows := []int{}
+	for y := 0; y < s.h; y += 1 {
+		if s.rows[y] {
+			rows = append(rows, y)
+		}
+	}
+	s.rows = map[int]bool{}
+	return rows
+}
+
+func TestDamage(t *testing.T) {
+	setup := func() (*VT, *testSurface) {
+		vt := New()
+		srf := newTestSurface(4, 4)
+		vt.SetSurface(srf)
+		vt.Resize(4, 4)
+		vt.Draw()
+		srf.painted()
+		return vt, srf
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		rects []Rect
+		drawn []int
+	}{
+		{
+			name:  "nothing",
+			input: "",
+			rects: nil,
+			drawn: []int{},
+		},
+		{
+			name:  "cursor movement",
+			input: "\x1b[3;2H",
+			rects: nil,
+			drawn: []int{},
+		},
+		{
+			name:  "print",
+			input: "\x1b[2;1Hab",
+			rects: []Rect{{X: 0, Y: 1, Width: 4, Height: 1}},
+			drawn: []int{1},
+		},
+		{
+			name:  "erase below",
+			input: "\x1b[3;1H\x1b[J",
+			rects: []Rect{{X: 0, Y: 2, Width: 4, Height: 2}},
+			drawn: []int{2, 3},
+		},
+		{
+			name:  "erase line",
+			input: "\x1b[1;1H\x1b[K\x1b[4;1H\x1b[K",
+			rects: []Rect{
+				{X: 0, Y: 0, Width: 4, Height: 1},
+				{X: 0, Y: 3, Width: 4, Height: 1},
+			},
+			drawn: []int{0, 3},
+		},
+		{
+			name:  "delete lines",
+			input: "\x1b[2;1H\x1b[M",
+			rects: []Rect{{X: 0, Y: 1, Width: 4, Height: 3}},
+			drawn: []int{1, 2, 3},
+		},
+		{
+			name:  "scroll region",
+			input: "\x1b[2;3r\x1b[3;1H\n",
+			rects: []Rect{{X: 0, Y: 1, Width: 4, Height: 2}},
+			drawn: []int{1, 2},
+		},
+		{
+			name:  "alternate screen",
+			input: "\x1b[?1049h",
+			rects: []Rect{{X: 0, Y: 0, Width: 4, Height: 4}},
+			drawn: []int{0, 1, 2, 3},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt, srf := setup()
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.rects, vt.Draw())
+			assert.Equal(t, test.drawn, srf.painted())
+			assert.Nil(t, vt.Draw())
+		})
+	}
+
+	t.Run("scrolled view", func(t *testing.T) {
+		vt, srf := setup()
+		_, _ = vt.Write([]byte("a\r\nb\r\nc\r\nd\r\ne"))
+		vt.Draw()
+		srf.painted()
+		vt.ScrollViewUp(1)
+		vt.Draw()
+		assert.Equal(t, []int{0, 1, 2, 3}, srf.painted())
+		assert.Equal(t, 'a', srf.cells[0][0])
+
+		// Damage to a grid row is drawn at its row in the view
+		_, _ = vt.Write([]byte("\x1b[1;2Hx"))
+		assert.Equal(t, []Rect{{X: 0, Y: 1, Width: 4, Height: 1}}, vt.Draw())
+		assert.Equal(t, 'x', srf.cells[1][1])
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		vt, srf := setup()
+		vt.Invalidate()
+		vt.Draw()
+		assert.Equal(t, []int{0, 1, 2, 3}, srf.painted())
+	})
+}
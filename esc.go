@@ -22,8 +22,10 @@ func (vt *VT) esc(esc string) {
 		vt.charsets.selected = g3
 	case "=":
 		// DECKPAM
+		vt.mode |= deckpam
 	case ">":
 		// DECKPNM
+		vt.mode &^= deckpam
 	case "c":
 		vt.ris()
 	case "(0":
@@ -163,12 +165,20 @@ func (vt *VT) ris() {
 		vt.altScreen[i] = make([]cell, w)
 		vt.primaryScreen[i] = make([]cell, w)
 	}
-	vt.margin.bottom = row(h) - 1
-	vt.margin.right = column(w) - 1
+	vt.margin = margin{
+		top:    0,
+		bottom: row(h) - 1,
+		left:   0,
+		right:  column(w) - 1,
+	}
 	vt.cursor.row = 0
 	vt.cursor.col = 0
 	vt.lastCol = false
 	vt.activeScreen = vt.primaryScreen
+	vt.damageAll()
+	vt.clearHistory()
+	vt.clearSelection()
+	vt.setHighlights(nil)
 	vt.charsets = charsets{
 		selected: 0,
 		saved:    0,
@@ -179,7 +189,13 @@ func (vt *VT) ris() {
 			g3: ascii,
 		},
 	}
+	vt.endSync()
 	vt.mode = decawm | dectcem
+	vt.vt52Graphics = false
+	vt.vt52Args = nil
+	vt.primaryKeyFlags = nil
+	vt.altKeyFlags = nil
+	vt.modifyOtherKeys = 0
 	vt.tabStop = []column{}
 	for i := 7; i < (50 * 7); i += 8 {
 		vt.tabStop = append(vt.tabStop, column(i))
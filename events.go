@@ -63,6 +63,20 @@ type EventBell struct {
 	*EventTerminal
 }
 
+// EventResizeRequest is emitted when the application changes the width of the
+// terminal, for example with DECCOLM. The terminal has already been resized:
+// hosts should resize the Surface to match
+type EventResizeRequest struct {
+	*EventTerminal
+	width  int
+	height int
+}
+
+// Size returns the new width and height of the terminal
+func (ev *EventResizeRequest) Size() (int, int) {
+	return ev.width, ev.height
+}
+
 type EventPanic struct {
 	*EventTerminal
 	Error error
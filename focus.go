@@ -0,0 +1,23 @@
+package tcellterm
+
+// SetFocused tells the terminal that the host gained or lost focus. If the
+// application enabled focus reporting with DECSET 1004, CSI I is sent when
+// focus is gained and CSI O when it is lost. A terminal starts focused, and
+// calls which don't change the focus send nothing
+func (vt *VT) SetFocused(focused bool) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.focused == focused {
+		return
+	}
+	vt.focused = focused
+	if vt.mode&focusEvents == 0 {
+		return
+	}
+	switch focused {
+	case true:
+		vt.send("\x1b[I")
+	case false:
+		vt.send("\x1b[O")
+	}
+}
@@ -0,0 +1,67 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFocused(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		focus    []bool
+		expected string
+	}{
+		{
+			name:     "mode reset",
+			focus:    []bool{false, true},
+			expected: "",
+		},
+		{
+			name:     "lost and gained",
+			input:    "\x1b[?1004h",
+			focus:    []bool{false, true},
+			expected: "\x1b[O\x1b[I",
+		},
+		{
+			name:     "only transitions",
+			input:    "\x1b[?1004h",
+			focus:    []bool{true, false, false, true, true},
+			expected: "\x1b[O\x1b[I",
+		},
+		{
+			name:     "disabled",
+			input:    "\x1b[?1004h\x1b[?1004l",
+			focus:    []bool{false, true},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 2)
+			buf := &strings.Builder{}
+			vt.ReplyWriter = buf
+			_, _ = vt.Write([]byte(test.input))
+			for _, focused := range test.focus {
+				vt.SetFocused(focused)
+			}
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestSetFocusedWhileReset(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	// Focus lost while the mode is reset is still tracked
+	vt.SetFocused(false)
+	_, _ = vt.Write([]byte("\x1b[?1004h"))
+	vt.SetFocused(false)
+	vt.SetFocused(true)
+	assert.Equal(t, "\x1b[I", buf.String())
+}
@@ -6,7 +6,16 @@ import (
 	"github.com/gdamore/tcell/v2"
 )
 
-func keyCode(ev *tcell.EventKey) string {
+// keyCode encodes a key event. The encoding of unmodified cursor and keypad
+// keys depends on the cursor key (DECCKM) and keypad (DECKPAM) modes in m, and
+// on VT52 mode
+func keyCode(ev *tcell.EventKey, m mode) string {
+	if m&vt52 != 0 {
+		// VT52 keys have no modifiers
+		if str, ok := vt52KeyCode(ev.Key(), m); ok {
+			return str
+		}
+	}
 	key := strings.Builder{}
 	switch ev.Modifiers() {
 	case tcell.ModNone:
@@ -14,7 +23,9 @@ func keyCode(ev *tcell.EventKey) string {
 		case tcell.KeyRune:
 			key.WriteRune(ev.Rune())
 		default:
-			if str, ok := keyCodes[ev.Key()]; ok {
+			if str, ok := modeKeyCode(ev.Key(), m); ok {
+				key.WriteString(str)
+			} else if str, ok := keyCodes[ev.Key()]; ok {
 				key.WriteString(str)
 			} else {
 				key.WriteRune(rune(ev.Key()))
@@ -606,6 +617,58 @@ func keyCode(ev *tcell.EventKey) string {
 	return key.String()
 }
 
+// modeKeyCode returns the encoding of an unmodified key which depends on the
+// modes. Cursor keys are sent as SS3 sequences in cursor key application mode,
+// and CSI sequences otherwise. The keys of the keypad are sent as SS3
+// sequences in keypad application mode, and as the editing keys they stand for
+// otherwise. tcell does not distinguish the Enter key of the keypad from the
+// main one, so it is not affected by the keypad mode. Enter sends CR LF in
+// line feed/new line mode. VT52 keys are handled by keyCode
+func modeKeyCode(k tcell.Key, m mode) (string, bool) {
+	if k == tcell.KeyEnter && m&lnm != 0 {
+		return "\r\n", true
+	}
+	cursor := "\x1b["
+	if m&decckm != 0 {
+		cursor = "\x1bO"
+	}
+	if m&deckpam != 0 {
+		switch k {
+		case tcell.KeyUpLeft:
+			return "\x1bOw", true
+		case tcell.KeyUpRight:
+			return "\x1bOy", true
+		case tcell.KeyCenter:
+			return "\x1bOu", true
+		case tcell.KeyDownLeft:
+			return "\x1bOq", true
+		case tcell.KeyDownRight:
+			return "\x1bOs", true
+		}
+	}
+	switch k {
+	case tcell.KeyUp:
+		return cursor + "A", true
+	case tcell.KeyDown:
+		return cursor + "B", true
+	case tcell.KeyRight:
+		return cursor + "C", true
+	case tcell.KeyLeft:
+		return cursor + "D", true
+	case tcell.KeyHome, tcell.KeyUpLeft:
+		return cursor + "H", true
+	case tcell.KeyEnd, tcell.KeyDownLeft:
+		return cursor + "F", true
+	case tcell.KeyCenter:
+		return cursor + "E", true
+	case tcell.KeyUpRight:
+		return info.KeyPgUp, true
+	case tcell.KeyDownRight:
+		return info.KeyPgDn, true
+	}
+	return "", false
+}
+
 var keyCodes = map[tcell.Key]string{
 	tcell.KeyBackspace: info.KeyBackspace,
 	tcell.KeyF1:        info.KeyF1,
@@ -1,6 +1,7 @@
 package tcellterm
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
@@ -98,8 +99,115 @@ func TestKey(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual := keyCode(test.event)
+			actual := keyCode(test.event, 0)
 			assert.Equal(t, test.expected, actual)
 		})
 	}
 }
+
+func TestKeyModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		key      tcell.Key
+		expected string
+	}{
+		{
+			name:     "cursor normal",
+			key:      tcell.KeyUp,
+			expected: "\x1b[A",
+		},
+		{
+			name:     "enter",
+			key:      tcell.KeyEnter,
+			expected: "\r",
+		},
+		{
+			name:     "enter new line",
+			input:    "\x1b[20h",
+			key:      tcell.KeyEnter,
+			expected: "\r\n",
+		},
+		{
+			name:     "enter new line reset",
+			input:    "\x1b[20h\x1b[20l",
+			key:      tcell.KeyEnter,
+			expected: "\r",
+		},
+		{
+			name:     "enter new line soft reset",
+			input:    "\x1b[20h\x1b[!p",
+			key:      tcell.KeyEnter,
+			expected: "\r",
+		},
+		{
+			name:     "enter new line hard reset",
+			input:    "\x1b[20h\x1bc",
+			key:      tcell.KeyEnter,
+			expected: "\r",
+		},
+		{
+			name:     "cursor application",
+			input:    "\x1b[?1h",
+			key:      tcell.KeyUp,
+			expected: "\x1bOA",
+		},
+		{
+			name:     "cursor reset",
+			input:    "\x1b[?1h\x1b[?1l",
+			key:      tcell.KeyLeft,
+			expected: "\x1b[D",
+		},
+		{
+			name:     "home application",
+			input:    "\x1b[?1h",
+			key:      tcell.KeyHome,
+			expected: "\x1bOH",
+		},
+		{
+			name:     "end normal",
+			key:      tcell.KeyEnd,
+			expected: "\x1b[F",
+		},
+		{
+			name:     "keypad numeric",
+			key:      tcell.KeyUpRight,
+			expected: "\x1b[5~",
+		},
+		{
+			name:     "keypad numeric with cursor application",
+			input:    "\x1b[?1h",
+			key:      tcell.KeyCenter,
+			expected: "\x1bOE",
+		},
+		{
+			name:     "keypad application",
+			input:    "\x1b=",
+			key:      tcell.KeyDownLeft,
+			expected: "\x1bOq",
+		},
+		{
+			name:     "keypad application reset",
+			input:    "\x1b=\x1b>",
+			key:      tcell.KeyDownLeft,
+			expected: "\x1b[F",
+		},
+		{
+			name:     "reset by RIS",
+			input:    "\x1b[?1h\x1b=\x1bc",
+			key:      tcell.KeyUpLeft,
+			expected: "\x1b[H",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 2)
+			buf := &strings.Builder{}
+			vt.ReplyWriter = buf
+			_, _ = vt.Write([]byte(test.input))
+			vt.HandleEvent(tcell.NewEventKey(test.key, 0, tcell.ModNone))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package tcellterm
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Progressive enhancement flags of the kitty keyboard protocol
+const (
+	kittyDisambiguate = 1 << iota
+	kittyEventTypes
+	kittyAlternateKeys
+	kittyAllKeys
+	kittyText
+
+	kittyAllFlags = kittyDisambiguate | kittyEventTypes | kittyAlternateKeys | kittyAllKeys | kittyText
+)
+
+// kittyPrivateKeys is the first number of the functional keys which are
+// numbered in the unicode private use area
+const kittyPrivateKeys = 57344
+
+// kittyStackSize is the maximum number of entries of a stack of keyboard
+// flags. When the stack is full, pushing drops the oldest entry
+const kittyStackSize = 32
+
+// keyFlags returns the stack of keyboard flags of the active screen. The
+// primary and alternate screens each have their own stack
+func (vt *VT) keyFlags() *[]int {
+	if vt.mode&smcup != 0 {
+		return &vt.altKeyFlags
+	}
+	return &vt.primaryKeyFlags
+}
+
+// keyboardFlags returns the current keyboard flags of the active screen
+func (vt *VT) keyboardFlags() int {
+	stack := *vt.keyFlags()
+	if len(stack) == 0 {
+		return 0
+	}
+	return stack[len(stack)-1]
+}
+
+// Push keyboard flags CSI > flags u
+func (vt *VT) pushKeyboardFlags(flags int) {
+	stack := vt.keyFlags()
+	if len(*stack) >= kittyStackSize {
+		*stack = (*stack)[1:]
+	}
+	*stack = append(*stack, flags&kittyAllFlags)
+}
+
+// Pop keyboard flags CSI < n u
+//
+// Pops n entries from the stack, default 1. Popping every entry resets the
+// flags
+func (vt *VT) popKeyboardFlags(n int) {
+	if n == 0 {
+		n = 1
+	}
+	stack := vt.keyFlags()
+	if n >= len(*stack) {
+		*stack = nil
+		return
+	}
+	*stack = (*stack)[:len(*stack)-n]
+}
+
+// Set keyboard flags CSI = flags ; mode u
+//
+// Replaces the current flags if mode is 1, the default. Mode 2 sets the given
+// flags and mode 3 resets them
+func (vt *VT) setKeyboardFlags(params []int) {
+	flags := ps(params) & kittyAllFlags
+	mode := 1
+	if len(params) > 1 && params[1] != 0 {
+		mode = params[1]
+	}
+	current := vt.keyboardFlags()
+	switch mode {
+	case 1:
+		current = flags
+	case 2:
+		current |= flags
+	case 3:
+		current &^= flags
+	default:
+		return
+	}
+	stack := vt.keyFlags()
+	if len(*stack) == 0 {
+		*stack = append(*stack, current)
+		return
+	}
+	(*stack)[len(*stack)-1] = current
+}
+
+// Query keyboard flags CSI ? u
+func (vt *VT) queryKeyboardFlags() {
+	vt.send(fmt.Sprintf("\x1b[?%du", vt.keyboardFlags()))
+}
+
+// kittyKey is a key as encoded by the kitty keyboard protocol
+type kittyKey struct {
+	// code is the unicode code point of the unshifted key, or the
+	// number of a functional key
+	code int
+	// final is the final byte of the escape code
+	final byte
+}
+
+// kittyFunctionalKeys are the keys which don't generate text
+var kittyFunctionalKeys = map[tcell.Key]kittyKey{
+	tcell.KeyEsc:        {27, 'u'},
+	tcell.KeyEnter:      {13, 'u'},
+	tcell.KeyTab:        {9, 'u'},
+	tcell.KeyBacktab:    {9, 'u'},
+	tcell.KeyBackspace:  {127, 'u'},
+	tcell.KeyBackspace2: {127, 'u'},
+	tcell.KeyInsert:     {2, '~'},
+	tcell.KeyDelete:     {3, '~'},
+	tcell.KeyLeft:       {1, 'D'},
+	tcell.KeyRight:      {1, 'C'},
+	tcell.KeyUp:         {1, 'A'},
+	tcell.KeyDown:       {1, 'B'},
+	tcell.KeyPgUp:       {5, '~'},
+	tcell.KeyPgDn:       {6, '~'},
+	tcell.KeyHome:       {1, 'H'},
+	tcell.KeyEnd:        {1, 'F'},
+	tcell.KeyPrint:      {57361, 'u'},
+	tcell.KeyPause:      {57362, 'u'},
+	tcell.KeyF1:         {1, 'P'},
+	tcell.KeyF2:         {1, 'Q'},
+	// CSI R is a cursor position report
+	tcell.KeyF3:  {13, '~'},
+	tcell.KeyF4:  {1, 'S'},
+	tcell.KeyF5:  {15, '~'},
+	tcell.KeyF6:  {17, '~'},
+	tcell.KeyF7:  {18, '~'},
+	tcell.KeyF8:  {19, '~'},
+	tcell.KeyF9:  {20, '~'},
+	tcell.KeyF10: {21, '~'},
+	tcell.KeyF11: {23, '~'},
+	tcell.KeyF12: {24, '~'},
+	// Keypad keys
+	tcell.KeyUpLeft:    {57423, 'u'},
+	tcell.KeyUpRight:   {57421, 'u'},
+	tcell.KeyCenter:    {57427, 'u'},
+	tcell.KeyDownLeft:  {57424, 'u'},
+	tcell.KeyDownRight: {57422, 'u'},
+}
+
+func init() {
+	// F13 through F35 are numbered from 57376
+	for k := tcell.KeyF13; k <= tcell.KeyF35; k += 1 {
+		kittyFunctionalKeys[k] = kittyKey{57376 + int(k-tcell.KeyF13), 'u'}
+	}
+}
+
+// modifierBits returns the modifier bits of a key, as used by xterm and the
+// kitty keyboard protocol. tcell reports the super key as ModMeta
+func modifierBits(mods tcell.ModMask) int {
+	m := 0
+	if mods&tcell.ModShift != 0 {
+		m |= 1
+	}
+	if mods&tcell.ModAlt != 0 {
+		m |= 2
+	}
+	if mods&tcell.ModCtrl != 0 {
+		m |= 4
+	}
+	if mods&tcell.ModMeta != 0 {
+		m |= 8
+	}
+	return m
+}
+
+// kittyKeyCode encodes a key event with the kitty keyboard protocol, using
+// the enhancements in flags. Keys which the protocol leaves in their legacy
+// encoding are encoded with keyCode. tcell only reports key presses, so no
+// release or repeat events are sent
+func kittyKeyCode(ev *tcell.EventKey, flags int, m mode) string {
+	mods := modifierBits(ev.Modifiers())
+	all := flags&kittyAllKeys != 0
+
+	if ev.Key() == tcell.KeyRune {
+		r := ev.Rune()
+		key := kittyKey{code: int(r), final: 'u'}
+		shifted := rune(0)
+		if unicode.IsUpper(r) {
+			// The key code is the unshifted key. An upper case
+			// letter implies shift
+			key.code = int(unicode.ToLower(r))
+			shifted = r
+			mods |= 1
+		}
+		if !all && mods&^1 == 0 {
+			// Text is sent as is
+			return string(r)
+		}
+		text := rune(0)
+		if mods&^1 == 0 {
+			text = r
+		}
+		if flags&kittyAlternateKeys == 0 || mods&1 == 0 {
+			shifted = 0
+		}
+		if flags&kittyText == 0 {
+			text = 0
+		}
+		return key.encode(mods, shifted, text)
+	}
+
+	key, ok := kittyFunctionalKeys[ev.Key()]
+	switch {
+	case ok:
+		if ev.Key() == tcell.KeyBacktab {
+			mods |= 1
+		}
+	case ev.Key() < ' ':
+		// Control characters are the keys of the C0 block with ctrl
+		mods |= 4
+		r := rune(ev.Key()) + '@'
+		if ev.Key() == tcell.KeyCtrlSpace {
+			r = ' '
+		}
+		key = kittyKey{code: int(unicode.ToLower(r)), final: 'u'}
+	default:
+		return keyCode(ev, m)
+	}
+	if all || mods != 0 {
+		return key.encode(mods, 0, 0)
+	}
+	switch {
+	case ev.Key() == tcell.KeyEsc:
+		// Escape is ambiguous with the start of escape codes
+		return key.encode(mods, 0, 0)
+	case key.code >= kittyPrivateKeys:
+		// Keys numbered in the private use area have no legacy
+		// encoding which can be told apart from other keys
+		return key.encode(mods, 0, 0)
+	}
+	return keyCode(ev, m)
+}
+
+// encode returns the escape code of the key with modifiers mods. shifted is
+// the shifted key and text the text of the key, if they are reported
+func (k kittyKey) encode(mods int, shifted rune, text rune) string {
+	b := strings.Builder{}
+	b.WriteString("\x1b[")
+	if k.final == 'u' || k.final == '~' || mods != 0 {
+		b.WriteString(fmt.Sprintf("%d", k.code))
+	}
+	if shifted != 0 {
+		b.WriteString(fmt.Sprintf(":%d", shifted))
+	}
+	if mods != 0 {
+		b.WriteString(fmt.Sprintf(";%d", mods+1))
+	}
+	if text != 0 {
+		if mods == 0 {
+			b.WriteString(";")
+		}
+		b.WriteString(fmt.Sprintf(";%d", text))
+	}
+	b.WriteByte(k.final)
+	return b.String()
+}
@@ -0,0 +1,348 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKittyFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "default",
+			input:    "\x1b[?u",
+			expected: "\x1b[?0u",
+		},
+		{
+			name:     "push",
+			input:    "\x1b[>1u\x1b[?u\x1b[>3u\x1b[?u",
+			expected: "\x1b[?1u\x1b[?3u",
+		},
+		{
+			name:     "push unknown flags",
+			input:    "\x1b[>255u\x1b[?u",
+			expected: "\x1b[?31u",
+		},
+		{
+			name:     "pop",
+			input:    "\x1b[>1u\x1b[>3u\x1b[<u\x1b[?u",
+			expected: "\x1b[?1u",
+		},
+		{
+			name:     "pop several",
+			input:    "\x1b[>1u\x1b[>3u\x1b[>7u\x1b[<2u\x1b[?u",
+			expected: "\x1b[?1u",
+		},
+		{
+			name:     "pop everything",
+			input:    "\x1b[>1u\x1b[<5u\x1b[?u\x1b[<u\x1b[?u",
+			expected: "\x1b[?0u\x1b[?0u",
+		},
+		{
+			name:     "set",
+			input:    "\x1b[>1u\x1b[=8u\x1b[?u\x1b[<u\x1b[?u",
+			expected: "\x1b[?8u\x1b[?0u",
+		},
+		{
+			name:     "set bits",
+			input:    "\x1b[>1u\x1b[=8;2u\x1b[?u",
+			expected: "\x1b[?9u",
+		},
+		{
+			name:     "reset bits",
+			input:    "\x1b[>11u\x1b[=9;3u\x1b[?u",
+			expected: "\x1b[?2u",
+		},
+		{
+			name:     "set with empty stack",
+			input:    "\x1b[=5u\x1b[?u",
+			expected: "\x1b[?5u",
+		},
+		{
+			name:     "alternate screen has its own stack",
+			input:    "\x1b[>1u\x1b[?1049h\x1b[?u\x1b[>8u\x1b[?u\x1b[?1049l\x1b[?u\x1b[?1049h\x1b[?u",
+			expected: "\x1b[?0u\x1b[?8u\x1b[?1u\x1b[?8u",
+		},
+		{
+			name:     "reset",
+			input:    "\x1b[>1u\x1b[?1049h\x1b[>1u\x1bc\x1b[?u\x1b[?1049h\x1b[?u",
+			expected: "\x1b[?0u\x1b[?0u",
+		},
+		{
+			name:     "decrc is not pop",
+			input:    "\x1b[>1u\x1b[u\x1b[?u",
+			expected: "\x1b[?1u",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 2)
+			buf := &strings.Builder{}
+			vt.ReplyWriter = buf
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestKittyStackSize(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	for i := 0; i < kittyStackSize+5; i += 1 {
+		_, _ = vt.Write([]byte("\x1b[>1u"))
+	}
+	assert.Len(t, vt.primaryKeyFlags, kittyStackSize)
+}
+
+func TestKittyKeyCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    int
+		mode     mode
+		event    *tcell.EventKey
+		expected string
+	}{
+		{
+			name:     "text",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			expected: "a",
+		},
+		{
+			name:     "shifted text",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			expected: "A",
+		},
+		{
+			name:     "ctrl",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'i', tcell.ModCtrl),
+			expected: "\x1b[105;5u",
+		},
+		{
+			name:     "ctrl control character",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl),
+			expected: "\x1b[97;5u",
+		},
+		{
+			name:     "ctrl space",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyCtrlSpace, 0, tcell.ModCtrl),
+			expected: "\x1b[32;5u",
+		},
+		{
+			name:     "alt",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt),
+			expected: "\x1b[120;3u",
+		},
+		{
+			name:     "ctrl alt shift",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModCtrl|tcell.ModAlt|tcell.ModShift),
+			expected: "\x1b[97;8u",
+		},
+		{
+			name:     "super",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModMeta),
+			expected: "\x1b[97;9u",
+		},
+		{
+			name:     "tab",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone),
+			expected: "\t",
+		},
+		{
+			name:     "enter",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+			expected: "\r",
+		},
+		{
+			name:     "backspace",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone),
+			expected: "\x7f",
+		},
+		{
+			name:     "ctrl enter",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModCtrl),
+			expected: "\x1b[13;5u",
+		},
+		{
+			name:     "backtab",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone),
+			expected: "\x1b[9;2u",
+		},
+		{
+			name:     "escape",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone),
+			expected: "\x1b[27u",
+		},
+		{
+			name:     "alt escape",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModAlt),
+			expected: "\x1b[27;3u",
+		},
+		{
+			name:     "arrow",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+			expected: "\x1b[A",
+		},
+		{
+			name:     "arrow in cursor key mode",
+			flags:    kittyDisambiguate,
+			mode:     decckm,
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+			expected: "\x1bOA",
+		},
+		{
+			name:     "ctrl arrow",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModCtrl),
+			expected: "\x1b[1;5D",
+		},
+		{
+			name:     "shift delete",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModShift),
+			expected: "\x1b[3;2~",
+		},
+		{
+			name:     "F1",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone),
+			expected: "\x1bOP",
+		},
+		{
+			name:     "ctrl F1",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModCtrl),
+			expected: "\x1b[1;5P",
+		},
+		{
+			name:     "ctrl F3",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyF3, 0, tcell.ModCtrl),
+			expected: "\x1b[13;5~",
+		},
+		{
+			name:     "F13",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyF13, 0, tcell.ModNone),
+			expected: "\x1b[57376u",
+		},
+		{
+			name:     "keypad",
+			flags:    kittyDisambiguate,
+			event:    tcell.NewEventKey(tcell.KeyCenter, 0, tcell.ModNone),
+			expected: "\x1b[57427u",
+		},
+		{
+			name:     "all keys text",
+			flags:    kittyDisambiguate | kittyAllKeys,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			expected: "\x1b[97u",
+		},
+		{
+			name:     "all keys shifted text",
+			flags:    kittyAllKeys,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			expected: "\x1b[97;2u",
+		},
+		{
+			name:     "all keys enter",
+			flags:    kittyAllKeys,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+			expected: "\x1b[13u",
+		},
+		{
+			name:     "all keys arrow",
+			flags:    kittyAllKeys,
+			mode:     decckm,
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+			expected: "\x1b[A",
+		},
+		{
+			name:     "all keys F5",
+			flags:    kittyAllKeys,
+			event:    tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModNone),
+			expected: "\x1b[15~",
+		},
+		{
+			name:     "alternate keys",
+			flags:    kittyAllKeys | kittyAlternateKeys,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			expected: "\x1b[97:65;2u",
+		},
+		{
+			name:     "alternate keys without shift",
+			flags:    kittyAllKeys | kittyAlternateKeys,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			expected: "\x1b[97u",
+		},
+		{
+			name:     "alternate keys with ctrl",
+			flags:    kittyDisambiguate | kittyAlternateKeys,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModCtrl|tcell.ModShift),
+			expected: "\x1b[97:65;6u",
+		},
+		{
+			name:     "text",
+			flags:    kittyAllKeys | kittyText,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			expected: "\x1b[97;;97u",
+		},
+		{
+			name:     "shifted text with text",
+			flags:    kittyAllKeys | kittyAlternateKeys | kittyText,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			expected: "\x1b[97:65;2;65u",
+		},
+		{
+			name:     "no text with ctrl",
+			flags:    kittyAllKeys | kittyText,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModCtrl),
+			expected: "\x1b[97;5u",
+		},
+		{
+			name:     "text without all keys",
+			flags:    kittyDisambiguate | kittyText,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'é', tcell.ModNone),
+			expected: "é",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := kittyKeyCode(test.event, test.flags, test.mode)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestKittyHandleEvent(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	vt.HandleEvent(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+	_, _ = vt.Write([]byte("\x1b[>1u"))
+	vt.HandleEvent(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+	assert.Equal(t, "\x1b\x1b[27u", buf.String())
+}
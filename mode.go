@@ -1,6 +1,10 @@
 package tcellterm
 
-type mode int
+import "fmt"
+
+// mode is a bitfield of the modes of the terminal. It is 64 bits wide so it
+// has room for every mode on all platforms
+type mode uint64
 
 const (
 	// ANSI-Standardized modes
@@ -18,8 +22,6 @@ const (
 	//
 	// Cursor Key mode
 	decckm
-	// ANSI/VT52 mode
-	decanm
 	// Column mode
 	deccolm
 	// Scroll mode
@@ -55,124 +57,311 @@ const (
 	mouseSGR
 	// Alternate scroll
 	altScroll
+	// Synchronized update
+	syncUpdate
+	// Keypad application mode
+	deckpam
+	// Left and right margin mode
+	declrmm
+	// Allow 80 to 132 column mode switching
+	allowColm
+	// Don't clear the screen when DECCOLM is set or reset
+	decncsm
+	// Focus event reporting
+	focusEvents
+	// X10 compatibility mouse
+	mouseX10
+	// UTF-8 mouse coordinates
+	mouseUTF8
+	// urxvt mouse encoding
+	mouseURXVT
+	// SGR mouse encoding in pixels
+	mouseSGRPixels
+	// Reverse video screen mode
+	decscnm
+	// VT52 mode, entered by resetting DECANM
+	vt52
 )
 
+// modeInfo describes a mode which can be set with SM or DECSET and reset with
+// RM or DECRST
+type modeInfo struct {
+	// flag is the bit of the mode field which reports the mode. Modes
+	// without a flag are always reported as reset
+	flag mode
+	// set and reset, if not nil, are called to set and reset the mode.
+	// Otherwise the flag is set or cleared
+	set   func(vt *VT)
+	reset func(vt *VT)
+	// permanent is set for modes which are recognized but can't be set
+	permanent bool
+	// inverted is set for modes which are reported as set while the flag
+	// is clear
+	inverted bool
+}
+
+// ansiModes are the modes set with SM and reset with RM
+var ansiModes = map[int]modeInfo{
+	2:  {flag: kam},
+	4:  {flag: irm},
+	12: {flag: srm},
+	20: {flag: lnm},
+}
+
+// decModes are the DEC private modes set with DECSET and reset with DECRST
+var decModes = map[int]modeInfo{
+	1: {flag: decckm},
+	2: {
+		flag:     vt52,
+		inverted: true,
+		set:      func(vt *VT) { vt.mode &^= vt52 },
+		reset: func(vt *VT) {
+			vt.mode |= vt52
+			vt.vt52Graphics = false
+		},
+	},
+	3: {
+		flag:  deccolm,
+		set:   func(vt *VT) { vt.setColumnMode(true) },
+		reset: func(vt *VT) { vt.setColumnMode(false) },
+	},
+	4: {flag: decsclm},
+	5: {
+		flag: decscnm,
+		set: func(vt *VT) {
+			vt.mode |= decscnm
+			vt.damageAll()
+		},
+		reset: func(vt *VT) {
+			vt.mode &^= decscnm
+			vt.damageAll()
+		},
+	},
+	6: {
+		flag: decom,
+		set: func(vt *VT) {
+			vt.mode |= decom
+			vt.home()
+		},
+		reset: func(vt *VT) {
+			vt.mode &^= decom
+			vt.home()
+		},
+	},
+	7: {
+		flag: decawm,
+		set: func(vt *VT) {
+			vt.mode |= decawm
+			vt.lastCol = false
+		},
+		reset: func(vt *VT) {
+			vt.mode &^= decawm
+			vt.lastCol = false
+		},
+	},
+	8:  {flag: decarm},
+	9:  {flag: mouseX10},
+	18: {flag: decpff},
+	19: {flag: decpex},
+	25: {flag: dectcem},
+	40: {flag: allowColm},
+	42: {flag: decnrcm},
+	47: {
+		flag:  smcup,
+		set:   (*VT).enterAltScreen,
+		reset: func(vt *VT) { vt.exitAltScreen(false) },
+	},
+	// Numeric keypad mode, also set by DECKPAM and reset by DECKPNM
+	66: {flag: deckpam},
+	69: {
+		flag: declrmm,
+		reset: func(vt *VT) {
+			vt.mode &^= declrmm
+			vt.margin.left = 0
+			vt.margin.right = column(vt.width() - 1)
+		},
+	},
+	95:   {flag: decncsm},
+	1000: {flag: mouseButtons},
+	1002: {flag: mouseDrag},
+	1003: {flag: mouseMotion},
+	1004: {flag: focusEvents},
+	1005: {flag: mouseUTF8},
+	1006: {flag: mouseSGR},
+	1007: {flag: altScroll},
+	1015: {flag: mouseURXVT},
+	1016: {flag: mouseSGRPixels},
+	1047: {
+		flag:  smcup,
+		set:   (*VT).enterAltScreen,
+		reset: func(vt *VT) { vt.exitAltScreen(true) },
+	},
+	1048: {
+		set:   (*VT).decsc,
+		reset: (*VT).decrc,
+	},
+	1049: {
+		flag: smcup,
+		set: func(vt *VT) {
+			vt.decsc()
+			vt.enterAltScreen()
+		},
+		reset: func(vt *VT) {
+			vt.exitAltScreen(true)
+			vt.decrc()
+		},
+	},
+	2004: {flag: paste},
+	2026: {
+		flag:  syncUpdate,
+		set:   (*VT).beginSync,
+		reset: (*VT).endSync,
+	},
+	// Grapheme clustering
+	2027: {permanent: true},
+}
+
+// Set Mode (SM) CSI Pm h
 func (vt *VT) sm(params []int) {
 	for _, param := range params {
-		switch param {
-		case 2:
-			vt.mode |= kam
-		case 4:
-			vt.mode |= irm
-		case 12:
-			vt.mode |= srm
-		case 20:
-			vt.mode |= lnm
-		}
+		vt.setMode(ansiModes, param, true)
 	}
 }
 
+// Reset Mode (RM) CSI Pm l
 func (vt *VT) rm(params []int) {
 	for _, param := range params {
-		switch param {
-		case 2:
-			vt.mode &^= kam
-		case 4:
-			vt.mode &^= irm
-		case 12:
-			vt.mode &^= srm
-		case 20:
-			vt.mode &^= lnm
-		}
+		vt.setMode(ansiModes, param, false)
 	}
 }
 
+// DEC Private Mode Set (DECSET) CSI ? Pm h
 func (vt *VT) decset(params []int) {
 	for _, param := range params {
-		switch param {
-		case 1:
-			vt.mode |= decckm
-		case 2:
-			vt.mode |= decanm
-		case 3:
-			vt.mode |= deccolm
-		case 4:
-			vt.mode |= decsclm
-		case 5:
-		case 6:
-			vt.mode |= decom
-		case 7:
-			vt.mode |= decawm
-			vt.lastCol = false
-		case 8:
-			vt.mode |= decarm
-		case 25:
-			vt.mode |= dectcem
-		case 1000:
-			vt.mode |= mouseButtons
-		case 1002:
-			vt.mode |= mouseDrag
-		case 1003:
-			vt.mode |= mouseMotion
-		case 1006:
-			vt.mode |= mouseSGR
-		case 1007:
-			vt.mode |= altScroll
-		case 1049:
-			vt.decsc()
-			vt.activeScreen = vt.altScreen
-			vt.mode |= smcup
-			// Enable altScroll in the alt screen. This is only used
-			// if the application doesn't enable mouse
-			vt.mode |= altScroll
-		case 2004:
-			vt.mode |= paste
-		}
+		vt.setMode(decModes, param, true)
 	}
 }
 
+// DEC Private Mode Reset (DECRST) CSI ? Pm l
 func (vt *VT) decrst(params []int) {
 	for _, param := range params {
-		switch param {
-		case 1:
-			vt.mode &^= decckm
-		case 2:
-			vt.mode &^= decanm
-		case 3:
-			vt.mode &^= deccolm
-		case 4:
-			vt.mode &^= decsclm
-		case 5:
-		case 6:
-			vt.mode &^= decom
-		case 7:
-			vt.mode &^= decawm
-			vt.lastCol = false
-		case 8:
-			vt.mode &^= decarm
-		case 25:
-			vt.mode &^= dectcem
-		case 1000:
-			vt.mode &^= mouseButtons
-		case 1002:
-			vt.mode &^= mouseDrag
-		case 1003:
-			vt.mode &^= mouseMotion
-		case 1006:
-			vt.mode &^= mouseSGR
-		case 1007:
-			vt.mode &^= altScroll
-		case 1049:
-			if vt.mode&smcup != 0 {
-				// Only clear if we were in the alternate
-				vt.ed(2)
-			}
-			vt.activeScreen = vt.primaryScreen
-			vt.mode &^= smcup
-			vt.mode &^= altScroll
-			vt.decrc()
-		case 2004:
-			vt.mode &^= paste
+		vt.setMode(decModes, param, false)
+	}
+}
+
+// setMode sets or resets mode ps of the table. Unknown modes are ignored
+func (vt *VT) setMode(modes map[int]modeInfo, ps int, set bool) {
+	info, ok := modes[ps]
+	if !ok || info.permanent {
+		return
+	}
+	switch {
+	case set && info.set != nil:
+		info.set(vt)
+	case !set && info.reset != nil:
+		info.reset(vt)
+	case set:
+		vt.mode |= info.flag
+	default:
+		vt.mode &^= info.flag
+	}
+}
+
+// Request Mode (DECRQM) CSI Ps $ p, CSI ? Ps $ p
+//
+// Reports the status of mode Ps. The reply is CSI Ps ; Pm $ y, with the same
+// prefix as the request. Pm is 0 if the mode is not recognized, 1 if it is set,
+// 2 if it is reset and 4 if it is permanently reset
+func (vt *VT) decrqm(ps int, private bool) {
+	modes := ansiModes
+	prefix := ""
+	if private {
+		modes = decModes
+		prefix = "?"
+	}
+	pm := 0
+	info, ok := modes[ps]
+	switch {
+	case !ok:
+		pm = 0
+	case info.permanent:
+		pm = 4
+	case info.flag != 0 && (vt.mode&info.flag != 0) != info.inverted:
+		pm = 1
+	default:
+		pm = 2
+	}
+	vt.send(fmt.Sprintf("\x1b[%s%d;%d$y", prefix, ps, pm))
+}
+
+// enterAltScreen switches to the alternate screen. The cursor is shared with
+// the primary screen
+func (vt *VT) enterAltScreen() {
+	if vt.mode&smcup != 0 {
+		return
+	}
+	vt.clearSelection()
+	vt.setHighlights(nil)
+	vt.activeScreen = vt.altScreen
+	vt.mode |= smcup
+	vt.damageAll()
+	// Enable altScroll in the alt screen. This is only used if the
+	// application doesn't enable mouse
+	vt.mode |= altScroll
+}
+
+// exitAltScreen switches back to the primary screen. If clear is set, the
+// alternate screen is erased first
+func (vt *VT) exitAltScreen(clear bool) {
+	if vt.mode&smcup == 0 {
+		return
+	}
+	if clear {
+		vt.ed(2)
+	}
+	vt.clearSelection()
+	vt.setHighlights(nil)
+	vt.activeScreen = vt.primaryScreen
+	vt.mode &^= smcup
+	vt.damageAll()
+	vt.mode &^= altScroll
+}
+
+// setColumnMode switches the terminal to 132 columns if wide is set, or to 80
+// columns otherwise. The switch is ignored unless it is allowed by mode 40.
+// The margins are reset and the cursor is moved home. Unless DECNCSM is set,
+// the screen is erased. If the width changes, the host is asked to resize the
+// Surface with an EventResizeRequest
+func (vt *VT) setColumnMode(wide bool) {
+	if vt.mode&allowColm == 0 {
+		return
+	}
+	w := 80
+	vt.mode &^= deccolm
+	if wide {
+		w = 132
+		vt.mode |= deccolm
+	}
+	h := vt.height()
+	if w != vt.width() {
+		vt.resize(w, h)
+		if vt.transport != nil {
+			_ = vt.transport.Resize(w, h, w*vt.cellWidth, h*vt.cellHeight)
 		}
+		vt.postEvent(&EventResizeRequest{
+			EventTerminal: newEventTerminal(vt),
+			width:         w,
+			height:        h,
+		})
+	}
+	vt.margin = margin{
+		top:    0,
+		bottom: row(h) - 1,
+		left:   0,
+		right:  column(w) - 1,
+	}
+	vt.home()
+	if vt.mode&decncsm == 0 {
+		vt.ed(2)
 	}
 }
@@ -0,0 +1,378 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAltScreen(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		screen string
+		row    int
+		col    int
+		alt    bool
+	}{
+		{
+			name:   "47 enter",
+			input:  "ab\x1b[?47h\x1b[2;2Hx",
+			screen: "   \n x ",
+			row:    1,
+			col:    2,
+			alt:    true,
+		},
+		{
+			name:   "47 exit keeps cursor and alt contents",
+			input:  "ab\x1b[?47h\x1b[2;2Hx\x1b[?47l",
+			screen: "ab \n   ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "47 alt contents are kept",
+			input:  "\x1b[?47hx\x1b[?47l\x1b[?47h",
+			screen: "x  \n   ",
+			row:    0,
+			col:    1,
+			alt:    true,
+		},
+		{
+			name:   "1047 exit clears alt",
+			input:  "\x1b[?1047hx\x1b[?1047l\x1b[?47h",
+			screen: "   \n   ",
+			row:    0,
+			col:    1,
+			alt:    true,
+		},
+		{
+			name:   "1047 exit keeps cursor",
+			input:  "ab\x1b[?1047h\x1b[2;3H\x1b[?1047l",
+			screen: "ab \n   ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "1048 saves cursor",
+			input:  "\x1b[2;2H\x1b[?1048h\x1b[H\x1b[?1048lx",
+			screen: "   \n x ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "1049 restores cursor",
+			input:  "ab\x1b[?1049h\x1b[2;2Hx\x1b[?1049l",
+			screen: "ab \n   ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "1049 exit clears alt",
+			input:  "\x1b[?1049hx\x1b[?1049l\x1b[?47h",
+			screen: "   \n   ",
+			row:    0,
+			col:    0,
+			alt:    true,
+		},
+		{
+			name:   "exit without enter",
+			input:  "ab\x1b[?47l\x1b[?1047l",
+			screen: "ab \n   ",
+			row:    0,
+			col:    2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(3, 2)
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.screen, vt.String())
+			row, col, _, _ := vt.Cursor()
+			assert.Equal(t, test.row, row, "row")
+			assert.Equal(t, test.col, col, "col")
+			assert.Equal(t, test.alt, vt.mode&smcup != 0, "alt screen")
+		})
+	}
+}
+
+func TestDECRQM(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "ansi reset",
+			input:    "\x1b[4$p",
+			expected: "\x1b[4;2$y",
+		},
+		{
+			name:     "ansi set",
+			input:    "\x1b[4h\x1b[4$p",
+			expected: "\x1b[4;1$y",
+		},
+		{
+			name:     "new line",
+			input:    "\x1b[20h\x1b[20$p",
+			expected: "\x1b[20;1$y",
+		},
+		{
+			name:     "ansi unknown",
+			input:    "\x1b[99$p",
+			expected: "\x1b[99;0$y",
+		},
+		{
+			name:     "private default set",
+			input:    "\x1b[?7$p",
+			expected: "\x1b[?7;1$y",
+		},
+		{
+			name:     "private reset",
+			input:    "\x1b[?25l\x1b[?25$p",
+			expected: "\x1b[?25;2$y",
+		},
+		{
+			name:     "bracketed paste",
+			input:    "\x1b[?2004h\x1b[?2004$p",
+			expected: "\x1b[?2004;1$y",
+		},
+		{
+			name:     "sgr mouse",
+			input:    "\x1b[?1006$p",
+			expected: "\x1b[?1006;2$y",
+		},
+		{
+			name:     "alternate screen",
+			input:    "\x1b[?1049h\x1b[?47$p\x1b[?1047$p\x1b[?1049$p",
+			expected: "\x1b[?47;1$y\x1b[?1047;1$y\x1b[?1049;1$y",
+		},
+		{
+			name:     "printer and national modes",
+			input:    "\x1b[?18h\x1b[?18$p\x1b[?19$p\x1b[?42h\x1b[?42$p",
+			expected: "\x1b[?18;1$y\x1b[?19;2$y\x1b[?42;1$y",
+		},
+		{
+			name:     "numeric keypad",
+			input:    "\x1b=\x1b[?66$p",
+			expected: "\x1b[?66;1$y",
+		},
+		{
+			name:     "reverse video",
+			input:    "\x1b[?5h\x1b[?5$p",
+			expected: "\x1b[?5;1$y",
+		},
+		{
+			name:     "permanently reset",
+			input:    "\x1b[?2027h\x1b[?2027$p",
+			expected: "\x1b[?2027;4$y",
+		},
+		{
+			name:     "private unknown",
+			input:    "\x1b[?12345$p",
+			expected: "\x1b[?12345;0$y",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 2)
+			buf := &strings.Builder{}
+			vt.ReplyWriter = buf
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestDECCOLM(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		width  int
+		row    int
+		col    int
+		text   string
+		resize bool
+	}{
+		{
+			name:  "not allowed",
+			input: "ab\x1b[?3h",
+			width: 80,
+			col:   2,
+			text:  "ab",
+		},
+		{
+			name:   "132 columns",
+			input:  "ab\x1b[?40h\x1b[2;2r\x1b[2;3H\x1b[?3h",
+			width:  132,
+			resize: true,
+		},
+		{
+			name:   "80 columns",
+			input:  "\x1b[?40h\x1b[?3h\x1b[?3l",
+			width:  80,
+			resize: true,
+		},
+		{
+			name:  "same width clears",
+			input: "ab\x1b[?40h\x1b[2;3H\x1b[?3l",
+			width: 80,
+		},
+		{
+			name:  "no clear",
+			input: "ab\x1b[?40h\x1b[?95h\x1b[2;3H\x1b[?3l",
+			width: 80,
+			text:  "ab",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(80, 3)
+			var ev *EventResizeRequest
+			vt.Attach(func(e tcell.Event) {
+				if e, ok := e.(*EventResizeRequest); ok {
+					ev = e
+				}
+			})
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.width, vt.width())
+			assert.Equal(t, 3, vt.height())
+			assert.Equal(t, test.text, strings.TrimRight(strings.Split(vt.String(), "\n")[0], " "))
+			row, col, _, _ := vt.Cursor()
+			assert.Equal(t, test.row, row, "row")
+			assert.Equal(t, test.col, col, "col")
+			assert.Equal(t, margin{bottom: 2, right: column(test.width - 1)}, vt.margin)
+			if !test.resize {
+				assert.Nil(t, ev)
+				return
+			}
+			if assert.NotNil(t, ev) {
+				w, h := ev.Size()
+				assert.Equal(t, test.width, w)
+				assert.Equal(t, 3, h)
+			}
+		})
+	}
+}
+
+func TestDECSCNM(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		reversed []bool
+	}{
+		{
+			name:     "reset",
+			input:    "\x1b[7ma\x1b[mb",
+			reversed: []bool{true, false},
+		},
+		{
+			name:     "set",
+			input:    "\x1b[?5h\x1b[7ma\x1b[mb",
+			reversed: []bool{false, true},
+		},
+		{
+			name:     "set and reset",
+			input:    "\x1b[?5h\x1b[7ma\x1b[mb\x1b[?5l",
+			reversed: []bool{true, false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 1)
+			srf := &frame{w: 2, h: 1}
+			vt.SetSurface(srf)
+			_, _ = vt.Write([]byte(test.input))
+			vt.Draw()
+			for i, cell := range srf.cells {
+				_, _, attrs := cell.style.Decompose()
+				assert.Equal(t, test.reversed[i], attrs&tcell.AttrReverse != 0, "col %d", i)
+			}
+			// Stored attributes are unchanged
+			_, _, attrs := vt.activeScreen[0][0].attrs.Decompose()
+			assert.NotZero(t, attrs&tcell.AttrReverse)
+		})
+	}
+}
+
+func TestDECSCNMColors(t *testing.T) {
+	red := tcell.PaletteColor(1)
+	blue := tcell.PaletteColor(4)
+	tests := []struct {
+		name  string
+		input string
+		// fg and bg are the colors drawn as the foreground and
+		// background, taking the reverse attribute into account
+		fg tcell.Color
+		bg tcell.Color
+	}{
+		{
+			name:  "default colors",
+			input: "a",
+			fg:    tcell.ColorDefault,
+			bg:    tcell.ColorDefault,
+		},
+		{
+			name:  "colored foreground",
+			input: "\x1b[31ma",
+			fg:    red,
+			bg:    tcell.ColorDefault,
+		},
+		{
+			name:  "colored background",
+			input: "\x1b[44ma",
+			fg:    tcell.ColorDefault,
+			bg:    blue,
+		},
+		{
+			name:  "colored foreground and background",
+			input: "\x1b[31;44ma",
+			fg:    red,
+			bg:    blue,
+		},
+		{
+			name:  "reversed colored foreground",
+			input: "\x1b[31;7ma",
+			fg:    tcell.ColorDefault,
+			bg:    red,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(1, 1)
+			srf := &frame{w: 1, h: 1}
+			vt.SetSurface(srf)
+			_, _ = vt.Write([]byte(test.input))
+			vt.Draw()
+			normal := srf.cells[0].style
+
+			srf.cells = nil
+			_, _ = vt.Write([]byte("\x1b[?5h"))
+			vt.Draw()
+			reversed := srf.cells[0].style
+
+			// Explicit colors keep their role, and the default colors
+			// trade places
+			fg, bg, attrs := normal.Decompose()
+			if attrs&tcell.AttrReverse != 0 {
+				fg, bg = bg, fg
+			}
+			assert.Equal(t, []tcell.Color{test.fg, test.bg}, []tcell.Color{fg, bg}, "normal")
+			fg, bg, attrs = reversed.Decompose()
+			if attrs&tcell.AttrReverse != 0 {
+				fg, bg = bg, fg
+			}
+			assert.Equal(t, []tcell.Color{test.fg, test.bg}, []tcell.Color{fg, bg}, "reversed")
+			// The default colors are swapped by drawing in reverse
+			_, _, normalAttrs := normal.Decompose()
+			assert.NotEqual(t, normalAttrs&tcell.AttrReverse, attrs&tcell.AttrReverse)
+		})
+	}
+}
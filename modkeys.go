@@ -0,0 +1,117 @@
+package tcellterm
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// The resource of XTMODKEYS which sets how keys without a modified encoding
+// of their own are sent
+const modifyOtherKeysResource = 4
+
+// Set key modifier options (XTMODKEYS) CSI > Pp ; Pv m
+//
+// Sets resource Pp to Pv. If Pv is omitted, the resource is reset, and if no
+// parameters are given every resource is reset. Only modifyOtherKeys is
+// supported
+func (vt *VT) xtmodkeys(params []int) {
+	if len(params) == 0 {
+		vt.modifyOtherKeys = 0
+		return
+	}
+	if params[0] != modifyOtherKeysResource {
+		return
+	}
+	level := 0
+	if len(params) > 1 {
+		level = params[1]
+	}
+	if level < 0 || level > 2 {
+		return
+	}
+	vt.modifyOtherKeys = level
+}
+
+// Disable key modifier options (XTMODKEYS) CSI > Pp n
+func (vt *VT) xtdismodkeys(params []int) {
+	if ps(params) == modifyOtherKeysResource {
+		vt.modifyOtherKeys = 0
+	}
+}
+
+// Query key modifier options (XTQMODKEYS) CSI ? Pp m
+//
+// Replies CSI > Pp ; Pv m
+func (vt *VT) xtqmodkeys(params []int) {
+	if ps(params) != modifyOtherKeysResource {
+		return
+	}
+	vt.send(fmt.Sprintf("\x1b[>%d;%dm", modifyOtherKeysResource, vt.modifyOtherKeys))
+}
+
+// otherKeyCode encodes a modified key with xterm's modifyOtherKeys at level.
+// At level 1, only keys with ctrl which have no control character are
+// encoded. At level 2, all modified keys are, except for shifted text. Keys
+// are encoded as CSI 27 ; mod ; code ~, or as CSI code ; mod u if format is
+// set. The returned bool is false if the key keeps its usual encoding
+func otherKeyCode(ev *tcell.EventKey, level int, format bool) (string, bool) {
+	mods := modifierBits(ev.Modifiers())
+	var code rune
+	switch ev.Key() {
+	case tcell.KeyRune:
+		code = ev.Rune()
+	case tcell.KeyEnter, tcell.KeyTab, tcell.KeyEsc:
+		code = rune(ev.Key())
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		code = 127
+	case tcell.KeyBacktab:
+		code = '\t'
+		mods |= 1
+	default:
+		if ev.Key() >= ' ' {
+			// Other keys have modified encodings of their own
+			return "", false
+		}
+		// Control characters are the keys of the C0 block with ctrl
+		mods |= 4
+		code = unicode.ToLower(rune(ev.Key()) + '@')
+		if ev.Key() == tcell.KeyCtrlSpace {
+			code = ' '
+		}
+	}
+	switch {
+	case mods == 0:
+		return "", false
+	case ev.Key() == tcell.KeyRune && mods == 1:
+		// Shifted text
+		return "", false
+	case level == 1 && !otherKeyLevel1(ev.Key(), code, mods):
+		return "", false
+	case level != 1 && level != 2:
+		return "", false
+	}
+	if format {
+		return fmt.Sprintf("\x1b[%d;%du", code, mods+1), true
+	}
+	return fmt.Sprintf("\x1b[27;%d;%d~", mods+1, code), true
+}
+
+// otherKeyLevel1 reports if a key is encoded at modifyOtherKeys level 1. Keys
+// are encoded if ctrl is held and they have no control character: ctrl with
+// letters, space and @[\]^_ keeps sending control characters
+func otherKeyLevel1(k tcell.Key, code rune, mods int) bool {
+	if mods&4 == 0 {
+		return false
+	}
+	switch {
+	case k < ' ' && k != tcell.KeyEnter && k != tcell.KeyTab && k != tcell.KeyEsc && k != tcell.KeyBackspace:
+		return false
+	case k == tcell.KeyRune && (unicode.IsLetter(code) && code < 0x80 || code == ' '):
+		return false
+	case k == tcell.KeyRune && code >= '@' && code <= '_':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXTMODKEYS(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "default",
+			input:    "\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+		{
+			name:     "level 2",
+			input:    "\x1b[>4;2m\x1b[?4m",
+			expected: "\x1b[>4;2m",
+		},
+		{
+			name:     "reset resource",
+			input:    "\x1b[>4;1m\x1b[>4m\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+		{
+			name:     "reset all",
+			input:    "\x1b[>4;1m\x1b[>m\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+		{
+			name:     "disable",
+			input:    "\x1b[>4;2m\x1b[>4n\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+		{
+			name:     "invalid level",
+			input:    "\x1b[>4;1m\x1b[>4;3m\x1b[?4m",
+			expected: "\x1b[>4;1m",
+		},
+		{
+			name:     "other resource",
+			input:    "\x1b[>1;2m\x1b[?1m\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+		{
+			name:     "reset",
+			input:    "\x1b[>4;2m\x1bc\x1b[?4m",
+			expected: "\x1b[>4;0m",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 2)
+			buf := &strings.Builder{}
+			vt.ReplyWriter = buf
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestOtherKeyCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    int
+		format   bool
+		event    *tcell.EventKey
+		expected string
+		ok       bool
+	}{
+		{
+			name:  "disabled",
+			event: tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModCtrl),
+		},
+		{
+			name:  "unmodified",
+			level: 2,
+			event: tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+		},
+		{
+			name:  "shifted text",
+			level: 2,
+			event: tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+		},
+		{
+			name:  "arrows keep their encoding",
+			level: 2,
+			event: tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModCtrl),
+		},
+		{
+			name:     "level 1 ctrl enter",
+			level:    1,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModCtrl),
+			expected: "\x1b[27;5;13~",
+			ok:       true,
+		},
+		{
+			name:     "level 1 ctrl semicolon",
+			level:    1,
+			event:    tcell.NewEventKey(tcell.KeyRune, ';', tcell.ModCtrl),
+			expected: "\x1b[27;5;59~",
+			ok:       true,
+		},
+		{
+			name:     "level 1 ctrl shift tab",
+			level:    1,
+			event:    tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModCtrl|tcell.ModShift),
+			expected: "\x1b[27;6;9~",
+			ok:       true,
+		},
+		{
+			name:  "level 1 ctrl letter",
+			level: 1,
+			event: tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModCtrl),
+		},
+		{
+			name:  "level 1 control character",
+			level: 1,
+			event: tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl),
+		},
+		{
+			name:  "level 1 ctrl bracket",
+			level: 1,
+			event: tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModCtrl),
+		},
+		{
+			name:  "level 1 alt",
+			level: 1,
+			event: tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt),
+		},
+		{
+			name:  "level 1 backtab",
+			level: 1,
+			event: tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone),
+		},
+		{
+			name:     "level 2 ctrl letter",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModCtrl),
+			expected: "\x1b[27;5;97~",
+			ok:       true,
+		},
+		{
+			name:     "level 2 control character",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl),
+			expected: "\x1b[27;5;97~",
+			ok:       true,
+		},
+		{
+			name:     "level 2 alt",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt),
+			expected: "\x1b[27;3;120~",
+			ok:       true,
+		},
+		{
+			name:     "level 2 backtab",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone),
+			expected: "\x1b[27;2;9~",
+			ok:       true,
+		},
+		{
+			name:     "level 2 shift enter",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModShift),
+			expected: "\x1b[27;2;13~",
+			ok:       true,
+		},
+		{
+			name:     "level 2 alt backspace",
+			level:    2,
+			event:    tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModAlt),
+			expected: "\x1b[27;3;127~",
+			ok:       true,
+		},
+		{
+			name:     "format ctrl enter",
+			level:    1,
+			format:   true,
+			event:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModCtrl),
+			expected: "\x1b[13;5u",
+			ok:       true,
+		},
+		{
+			name:     "format ctrl alt letter",
+			level:    2,
+			format:   true,
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModCtrl|tcell.ModAlt),
+			expected: "\x1b[97;7u",
+			ok:       true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, ok := otherKeyCode(test.event, test.level, test.format)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestOtherKeysHandleEvent(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	ev := tcell.NewEventKey(tcell.KeyRune, ';', tcell.ModCtrl)
+	vt.HandleEvent(ev)
+	_, _ = vt.Write([]byte("\x1b[>4;1m"))
+	vt.HandleEvent(ev)
+	vt.FormatOtherKeys = true
+	vt.HandleEvent(ev)
+	assert.Equal(t, ";\x1b[27;5;59~\x1b[59;5u", buf.String())
+}
@@ -2,108 +2,207 @@ package tcellterm
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 )
 
+// mouseTracking are the modes which enable mouse reporting. The SGR encodings
+// report all events when no other mode is set
+const mouseTracking = mouseX10 | mouseButtons | mouseDrag | mouseMotion | mouseSGR | mouseSGRPixels
+
+// wheelButtons are the buttons reported for wheel motion. Wheel events have no
+// release
+const wheelButtons = tcell.WheelUp | tcell.WheelDown | tcell.WheelLeft | tcell.WheelRight
+
+// The largest positions which can be encoded with the legacy and UTF-8
+// encodings. Larger positions are sent as a NUL byte
+const (
+	mouseLimit     = 255 - 32
+	mouseUTF8Limit = 2047 - 32
+)
+
+// mouseEvent is the kind of a mouse event
+type mouseEvent int
+
+const (
+	mousePress mouseEvent = iota
+	mouseRelease
+	// mouseDragEvent is motion with a button held
+	mouseDragEvent
+	// mouseMove is motion with no button held
+	mouseMove
+)
+
 func (vt *VT) handleMouse(ev *tcell.EventMouse) string {
-	if vt.mode&mouseButtons == 0 && vt.mode&mouseDrag == 0 && vt.mode&mouseMotion == 0 && vt.mode&mouseSGR == 0 {
+	if vt.mode&mouseTracking == 0 {
 		if vt.mode&altScroll != 0 && vt.mode&smcup != 0 {
 			// Translate wheel motion into arrows up and down
 			// 3x rows
 			if ev.Buttons()&tcell.WheelUp != 0 {
-				vt.pty.WriteString(info.KeyUp)
-				vt.pty.WriteString(info.KeyUp)
-				vt.pty.WriteString(info.KeyUp)
+				up := keyCode(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), vt.mode)
+				vt.send(strings.Repeat(up, 3))
 			}
 			if ev.Buttons()&tcell.WheelDown != 0 {
-				vt.pty.WriteString(info.KeyDown)
-				vt.pty.WriteString(info.KeyDown)
-				vt.pty.WriteString(info.KeyDown)
+				down := keyCode(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone), vt.mode)
+				vt.send(strings.Repeat(down, 3))
 			}
 		}
 		return ""
 	}
-	// Return early if we aren't reporting motion or drag events
-	if vt.mode&mouseButtons != 0 && vt.mouseBtn == ev.Buttons() {
-		// motion or drag
-		return ""
-	}
 
-	if vt.mode&mouseDrag != 0 && vt.mouseBtn == tcell.ButtonNone && ev.Buttons() == tcell.ButtonNone {
-		// Motion event
-		return ""
-	}
+	sgr := vt.mode&(mouseSGR|mouseSGRPixels) != 0
+	x10 := vt.mode&(mouseButtons|mouseDrag|mouseMotion) == 0 && vt.mode&mouseX10 != 0
+	prev := vt.mouseBtn
+	held := ev.Buttons() &^ wheelButtons
+	vt.mouseBtn = held
 
-	// Encode the button
-	var b int
-	if ev.Buttons()&tcell.Button1 != 0 {
-		b += 0
-	}
-	if ev.Buttons()&tcell.Button3 != 0 {
-		b += 1
+	var kind mouseEvent
+	b := 0
+	switch {
+	case ev.Buttons()&wheelButtons != 0:
+		kind = mousePress
+		b = wheelCode(ev.Buttons())
+	case held == tcell.ButtonNone && prev != tcell.ButtonNone:
+		kind = mouseRelease
+		// Only the SGR encodings report which button was released
+		b = 3
+		if sgr {
+			b = buttonCode(prev)
+		}
+	case held == tcell.ButtonNone:
+		kind = mouseMove
+		b = 3
+	case held == prev:
+		kind = mouseDragEvent
+		b = buttonCode(held)
+	default:
+		kind = mousePress
+		b = buttonCode(held)
 	}
-	if ev.Buttons()&tcell.Button2 != 0 {
-		b += 2
+
+	if !vt.reportsMouse(kind) {
+		return ""
 	}
-	if ev.Buttons() == tcell.ButtonNone {
-		b += 3
+	if kind == mouseDragEvent || kind == mouseMove {
+		b += 32
 	}
-	if ev.Buttons()&tcell.WheelUp != 0 {
-		b += 0 + 64
+	// X10 compatibility mode doesn't report modifiers
+	if !x10 {
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			b += 4
+		}
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			b += 8
+		}
+		if ev.Modifiers()&tcell.ModCtrl != 0 {
+			b += 16
+		}
 	}
-	if ev.Buttons()&tcell.WheelDown != 0 {
-		b += 1 + 64
+
+	col, row := ev.Position()
+	final := 'M'
+	if kind == mouseRelease {
+		final = 'm'
 	}
-	if ev.Modifiers()&tcell.ModShift != 0 {
-		b += 4
+	switch {
+	case vt.mode&mouseSGRPixels != 0:
+		// Positions are the top left pixel of the cell. Without a
+		// cell size, cells are reported as one pixel
+		cw, ch := vt.cellWidth, vt.cellHeight
+		if cw == 0 || ch == 0 {
+			cw, ch = 1, 1
+		}
+		return fmt.Sprintf("\x1b[<%d;%d;%d%c", b, col*cw+1, row*ch+1, final)
+	case vt.mode&mouseSGR != 0:
+		return fmt.Sprintf("\x1b[<%d;%d;%d%c", b, col+1, row+1, final)
 	}
-	if ev.Modifiers()&tcell.ModAlt != 0 {
-		b += 8
+
+	if vt.mode&mouseURXVT != 0 {
+		return fmt.Sprintf("\x1b[%d;%d;%dM", 32+b, col+1, row+1)
 	}
-	if ev.Modifiers()&tcell.ModCtrl != 0 {
-		b += 16
+	buf := []byte{'\x1b', '[', 'M', byte(32 + b)}
+	if vt.mode&mouseUTF8 != 0 {
+		buf = appendUTF8Position(buf, col)
+		buf = appendUTF8Position(buf, row)
+		return string(buf)
 	}
+	buf = appendPosition(buf, col)
+	buf = appendPosition(buf, row)
+	return string(buf)
+}
 
-	if vt.mode&mouseButtons == 0 && vt.mouseBtn != tcell.ButtonNone && ev.Buttons() != tcell.ButtonNone {
-		// drag event
-		b += 32
+// reportsMouse reports if an event of kind is sent to the application in the
+// current mouse mode
+func (vt *VT) reportsMouse(kind mouseEvent) bool {
+	switch {
+	case vt.mode&mouseMotion != 0:
+		return true
+	case vt.mode&mouseDrag != 0:
+		return kind != mouseMove
+	case vt.mode&mouseButtons != 0:
+		return kind == mousePress || kind == mouseRelease
+	case vt.mode&mouseX10 != 0:
+		return kind == mousePress
+	default:
+		// SGR encoding without a tracking mode
+		return true
 	}
+}
 
-	col, row := ev.Position()
-
-	if vt.mode&mouseSGR != 0 {
-		switch {
-		case ev.Buttons()&tcell.WheelUp != 0:
-			return fmt.Sprintf("\x1b[<%d;%d;%dM", b, col+1, row+1)
-
-		case ev.Buttons()&tcell.WheelDown != 0:
-			return fmt.Sprintf("\x1b[<%d;%d;%dM", b, col+1, row+1)
-
-		case ev.Buttons() == tcell.ButtonNone && vt.mouseBtn != tcell.ButtonNone:
-			// Button was in, and now it's not
-			var button int
-			switch vt.mouseBtn {
-			case tcell.Button1:
-				button = 0
-			case tcell.Button3:
-				button = 1
-			case tcell.Button2:
-				button = 2
-			}
-			vt.mouseBtn = ev.Buttons()
-			return fmt.Sprintf("\x1b[<%d;%d;%dm", button, col+1, row+1)
+// buttonCode returns the code of the lowest numbered button held in btn
+func buttonCode(btn tcell.ButtonMask) int {
+	switch {
+	case btn&tcell.Button1 != 0:
+		return 0
+	case btn&tcell.Button3 != 0:
+		return 1
+	case btn&tcell.Button2 != 0:
+		return 2
+	// Buttons 8 through 11
+	case btn&tcell.Button4 != 0:
+		return 128
+	case btn&tcell.Button5 != 0:
+		return 129
+	case btn&tcell.Button6 != 0:
+		return 130
+	case btn&tcell.Button7 != 0:
+		return 131
+	}
+	return 3
+}
 
-		default:
-			vt.mouseBtn = ev.Buttons()
-			return fmt.Sprintf("\x1b[<%d;%d;%dM", b, col+1, row+1)
-		}
+// wheelCode returns the code of wheel motion in btn, reported as buttons 4
+// through 7
+func wheelCode(btn tcell.ButtonMask) int {
+	switch {
+	case btn&tcell.WheelUp != 0:
+		return 64
+	case btn&tcell.WheelDown != 0:
+		return 65
+	case btn&tcell.WheelLeft != 0:
+		return 66
 	}
+	return 67
+}
 
-	encodedCol := 32 + col + 1
-	encodedRow := 32 + row + 1
-	b += 32
+// appendPosition appends a 0-based position in the legacy encoding, as a
+// single byte
+func appendPosition(buf []byte, pos int) []byte {
+	if pos >= mouseLimit {
+		return append(buf, 0)
+	}
+	return append(buf, byte(32+pos+1))
+}
 
-	vt.mouseBtn = ev.Buttons()
-	return fmt.Sprintf("\x1b[M%c%c%c", b, encodedCol, encodedRow)
+// appendUTF8Position appends a 0-based position in the UTF-8 encoding, where
+// positions which don't fit in a byte are encoded as a UTF-8 character
+func appendUTF8Position(buf []byte, pos int) []byte {
+	if pos >= mouseUTF8Limit {
+		return append(buf, 0)
+	}
+	p := make([]byte, utf8.UTFMax)
+	n := utf8.EncodeRune(p, rune(32+pos+1))
+	return append(buf, p[:n]...)
 }
@@ -1,6 +1,7 @@
 package tcellterm
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
@@ -62,7 +63,378 @@ func TestHandleMouse(t *testing.T) {
 			mode:     mouseSGR,
 			button:   tcell.ButtonNone,
 			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
-			expected: "\x1b[<3;1;1M",
+			expected: "\x1b[<35;1;1M",
+		},
+		{
+			name:     "x10 press",
+			mode:     mouseX10,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M !!",
+		},
+		{
+			name:     "x10 press ignores modifiers",
+			mode:     mouseX10,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModShift|tcell.ModCtrl),
+			expected: "\x1b[M !!",
+		},
+		{
+			name:     "x10 release",
+			mode:     mouseX10,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "",
+		},
+		{
+			name:     "x10 drag",
+			mode:     mouseX10,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(1, 0, tcell.Button1, tcell.ModNone),
+			expected: "",
+		},
+		{
+			name:     "x10 motion",
+			mode:     mouseX10,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(1, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "",
+		},
+		{
+			name:     "x10 wheel",
+			mode:     mouseX10,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone),
+			expected: "\x1b[M`!!",
+		},
+		{
+			name:     "normal motion",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(1, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "",
+		},
+		{
+			name:     "normal wheel up",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone),
+			expected: "\x1b[M`!!",
+		},
+		{
+			name:     "normal wheel down",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelDown, tcell.ModNone),
+			expected: "\x1b[Ma!!",
+		},
+		{
+			name:     "normal wheel left",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelLeft, tcell.ModNone),
+			expected: "\x1b[Mb!!",
+		},
+		{
+			name:     "normal wheel right",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelRight, tcell.ModNone),
+			expected: "\x1b[Mc!!",
+		},
+		{
+			name:     "normal repeated wheel",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone),
+			expected: "\x1b[M`!!",
+		},
+		{
+			name:     "normal wheel with button held",
+			mode:     mouseButtons,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1|tcell.WheelDown, tcell.ModNone),
+			expected: "\x1b[Ma!!",
+		},
+		{
+			name:     "drag motion",
+			mode:     mouseDrag,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(1, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "",
+		},
+		{
+			name:     "drag press",
+			mode:     mouseDrag,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M !!",
+		},
+		{
+			name:     "drag release",
+			mode:     mouseDrag,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[M#!!",
+		},
+		{
+			name:     "any motion",
+			mode:     mouseMotion,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(1, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[MC\"!",
+		},
+		{
+			name:     "any drag",
+			mode:     mouseMotion,
+			button:   tcell.Button3,
+			event:    tcell.NewEventMouse(1, 0, tcell.Button3, tcell.ModNone),
+			expected: "\x1b[MA\"!",
+		},
+		{
+			name:     "normal and drag report drag",
+			mode:     mouseButtons | mouseDrag,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M@!!",
+		},
+		{
+			name:     "normal and any report motion",
+			mode:     mouseButtons | mouseMotion,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[MC!!",
+		},
+		{
+			name:     "button 3",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button3, tcell.ModNone),
+			expected: "\x1b[M!!!",
+		},
+		{
+			name:     "button 2",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button2, tcell.ModNone),
+			expected: "\x1b[M\"!!",
+		},
+		{
+			name:     "button 8",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button4, tcell.ModNone),
+			expected: "\x1b[M\xa0!!",
+		},
+		{
+			name:     "button 9",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button5, tcell.ModNone),
+			expected: "\x1b[M\xa1!!",
+		},
+		{
+			name:     "button 10",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button6, tcell.ModNone),
+			expected: "\x1b[M\xa2!!",
+		},
+		{
+			name:     "button 11",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button7, tcell.ModNone),
+			expected: "\x1b[M\xa3!!",
+		},
+		{
+			name:     "button 8 release",
+			mode:     mouseButtons,
+			button:   tcell.Button4,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[M#!!",
+		},
+		{
+			name:     "button 1 + alt",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModAlt),
+			expected: "\x1b[M(!!",
+		},
+		{
+			name:     "button 1 + ctrl",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModCtrl),
+			expected: "\x1b[M0!!",
+		},
+		{
+			name:     "button 1 + shift + alt + ctrl",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModShift|tcell.ModAlt|tcell.ModCtrl),
+			expected: "\x1b[M<!!",
+		},
+		{
+			name:     "release + ctrl",
+			mode:     mouseButtons,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModCtrl),
+			expected: "\x1b[M3!!",
+		},
+		{
+			name:     "wheel + shift",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModShift),
+			expected: "\x1b[Md!!",
+		},
+		{
+			name:     "drag + alt",
+			mode:     mouseDrag,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModAlt),
+			expected: "\x1b[MH!!",
+		},
+		{
+			name:     "legacy position",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(9, 4, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M *%",
+		},
+		{
+			name:     "legacy last position",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(222, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M \xff!",
+		},
+		{
+			name:     "legacy position out of range",
+			mode:     mouseButtons,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(223, 300, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M \x00\x00",
+		},
+		{
+			name:     "utf8 position",
+			mode:     mouseButtons | mouseUTF8,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(9, 4, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M *%",
+		},
+		{
+			name:     "utf8 large position",
+			mode:     mouseButtons | mouseUTF8,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(95, 300, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M \u0080\u014d",
+		},
+		{
+			name:     "utf8 position out of range",
+			mode:     mouseButtons | mouseUTF8,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(2015, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[M \x00!",
+		},
+		{
+			name:     "utf8 release",
+			mode:     mouseButtons | mouseUTF8,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[M#!!",
+		},
+		{
+			name:     "urxvt press",
+			mode:     mouseButtons | mouseURXVT,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(299, 4, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[32;300;5M",
+		},
+		{
+			name:     "urxvt release",
+			mode:     mouseButtons | mouseURXVT,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[35;1;1M",
+		},
+		{
+			name:     "urxvt wheel + ctrl",
+			mode:     mouseButtons | mouseURXVT,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelDown, tcell.ModCtrl),
+			expected: "\x1b[113;1;1M",
+		},
+		{
+			name:     "sgr large position",
+			mode:     mouseButtons | mouseSGR,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(299, 4, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[<0;300;5M",
+		},
+		{
+			name:     "sgr release",
+			mode:     mouseButtons | mouseSGR,
+			button:   tcell.Button3,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[<1;1;1m",
+		},
+		{
+			name:     "sgr button 11 release",
+			mode:     mouseButtons | mouseSGR,
+			button:   tcell.Button7,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[<131;1;1m",
+		},
+		{
+			name:     "sgr release + shift",
+			mode:     mouseButtons | mouseSGR,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModShift),
+			expected: "\x1b[<4;1;1m",
+		},
+		{
+			name:     "sgr drag",
+			mode:     mouseDrag | mouseSGR,
+			button:   tcell.Button2,
+			event:    tcell.NewEventMouse(2, 3, tcell.Button2, tcell.ModNone),
+			expected: "\x1b[<34;3;4M",
+		},
+		{
+			name:     "sgr wheel",
+			mode:     mouseButtons | mouseSGR,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone),
+			expected: "\x1b[<64;1;1M",
+		},
+		{
+			name:     "sgr takes precedence",
+			mode:     mouseButtons | mouseUTF8 | mouseURXVT | mouseSGR,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[<0;1;1M",
+		},
+		{
+			name:     "sgr pixels without cell size",
+			mode:     mouseButtons | mouseSGRPixels,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(9, 4, tcell.Button1, tcell.ModNone),
+			expected: "\x1b[<0;10;5M",
+		},
+		{
+			name:     "sgr pixels release",
+			mode:     mouseButtons | mouseSGRPixels,
+			button:   tcell.Button1,
+			event:    tcell.NewEventMouse(0, 0, tcell.ButtonNone, tcell.ModNone),
+			expected: "\x1b[<0;1;1m",
+		},
+		{
+			name:     "x10 sgr",
+			mode:     mouseX10 | mouseSGR,
+			button:   tcell.ButtonNone,
+			event:    tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModAlt),
+			expected: "\x1b[<0;1;1M",
 		},
 	}
 	for _, test := range tests {
@@ -75,3 +447,23 @@ func TestHandleMouse(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleMouseSGRPixels(t *testing.T) {
+	vt := New()
+	vt.SetCellSize(8, 16)
+	vt.mode |= mouseButtons | mouseSGRPixels
+	actual := vt.handleMouse(tcell.NewEventMouse(2, 3, tcell.Button1, tcell.ModNone))
+	assert.Equal(t, "\x1b[<0;17;49M", actual)
+	actual = vt.handleMouse(tcell.NewEventMouse(2, 3, tcell.ButtonNone, tcell.ModNone))
+	assert.Equal(t, "\x1b[<0;17;49m", actual)
+}
+
+func TestMouseModes(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	_, _ = vt.Write([]byte("\x1b[?9h\x1b[?1005h\x1b[?1015h\x1b[?1016h"))
+	_, _ = vt.Write([]byte("\x1b[?9$p\x1b[?1005$p\x1b[?1015$p\x1b[?1016$p"))
+	assert.Equal(t, "\x1b[?9;1$y\x1b[?1005;1$y\x1b[?1015;1$y\x1b[?1016;1$y", buf.String())
+}
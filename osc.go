@@ -19,8 +19,7 @@ func (vt *VT) osc(data string) {
 	case "8":
 		if vt.OSC8 {
 			url, id := osc8(val)
-			vt.cursor.attrs = vt.cursor.attrs.Url(url)
-			vt.cursor.attrs = vt.cursor.attrs.UrlId(id)
+			vt.cursor.ext.link = hyperlink{url: url, id: id}
 		}
 	}
 }
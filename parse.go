@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 const eof rune = -1
@@ -30,6 +31,13 @@ type Parser struct {
 	final        rune
 
 	oscData []rune
+
+	// handler, if set, receives sequences as they are parsed instead of
+	// the sequences channel. It is used by parsers fed with feed
+	handler func(Sequence)
+	// partial holds an incomplete UTF-8 encoding from the end of the last
+	// call to feed
+	partial []byte
 }
 
 func NewParser(r io.Reader) *Parser {
@@ -62,6 +70,37 @@ func (p *Parser) Next() Sequence {
 	return <-p.sequences
 }
 
+// newFeedParser returns a Parser which parses bytes passed to feed. Each
+// sequence is passed to fn as it is parsed, rather than being returned by Next
+func newFeedParser(fn func(Sequence)) *Parser {
+	return &Parser{
+		state:   ground,
+		handler: fn,
+	}
+}
+
+// feed parses b synchronously. An incomplete UTF-8 encoding at the end of b is
+// held until the next call. Invalid UTF-8 is delivered byte by byte, the same
+// as when reading from an io.Reader
+func (p *Parser) feed(b []byte) {
+	if len(p.partial) > 0 {
+		b = append(p.partial, b...)
+		p.partial = nil
+	}
+	for len(b) > 0 {
+		if !utf8.FullRune(b) {
+			p.partial = append([]byte{}, b...)
+			return
+		}
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && n == 1 {
+			r = rune(b[0])
+		}
+		b = b[n:]
+		p.state = anywhere(r, p)
+	}
+}
+
 func (p *Parser) run() {
 	for {
 		r := p.readRune()
@@ -96,6 +135,10 @@ func (p *Parser) readRune() rune {
 }
 
 func (p *Parser) emit(seq Sequence) {
+	if p.handler != nil {
+		p.handler(seq)
+		return
+	}
 	p.sequences <- seq
 }
 
@@ -188,7 +231,8 @@ func (p *Parser) param(r rune) {
 // character, and execute it, passing in the parameter list.
 //
 // csiDispatch will normalize SGR RGB sequences to a maximum of 5 parameters. IE
-// '38:2::0:0:0' will return []int{38,2,0,0,0}
+// '38:2::0:0:0' will return []int{38,2,0,0,0}. The parameters as sent are kept
+// in Subparameters
 func (p *Parser) csiDispatch(r rune) {
 	csi := CSI{
 		Final:        r,
@@ -200,6 +244,14 @@ func (p *Parser) csiDispatch(r rune) {
 		return
 	}
 	paramStrRaw := strings.Split(string(p.params), ";")
+	if strings.Contains(string(p.params), ":") {
+		sub, err := subparameters(paramStrRaw)
+		if err != nil {
+			p.emit(fmt.Errorf("csiDispatch: %w", err))
+			return
+		}
+		csi.Subparameters = sub
+	}
 	paramStr := make([]string, 0, len(paramStrRaw))
 	for _, param := range paramStrRaw {
 		if !strings.Contains(param, ":") {
@@ -242,6 +294,29 @@ func (p *Parser) csiDispatch(r rune) {
 	p.emit(csi)
 }
 
+// subparameters splits each parameter into its colon separated values. Empty
+// values are 0
+func subparameters(params []string) ([][]int, error) {
+	sub := make([][]int, 0, len(params))
+	for _, param := range params {
+		strs := strings.Split(param, ":")
+		vals := make([]int, 0, len(strs))
+		for _, str := range strs {
+			if str == "" {
+				vals = append(vals, 0)
+				continue
+			}
+			val, err := strconv.Atoi(str)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		sub = append(sub, vals)
+	}
+	return sub, nil
+}
+
 // When the control function OSC (Operating System Command) is recognised,
 // this action initializes an external parser (the “OSC Handler”) to handle
 // the characters from the control string. OSC control strings are not
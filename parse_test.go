@@ -232,6 +232,9 @@ func TestCSI(t *testing.T) {
 					Final:        'm',
 					Parameters:   []int{38, 2, 0, 0, 0},
 					Intermediate: []rune{},
+					Subparameters: [][]int{
+						{38, 2, 0, 0, 0, 0},
+					},
 				},
 			},
 		},
@@ -244,6 +247,26 @@ func TestCSI(t *testing.T) {
 					Final:        'm',
 					Parameters:   []int{38, 2, 0, 0, 0, 48, 2, 0, 0, 0},
 					Intermediate: []rune{},
+					Subparameters: [][]int{
+						{38, 2, 0, 0, 0, 0},
+						{48, 2, 0, 0, 0, 0},
+					},
+				},
+			},
+		},
+		{
+			name:  "CSI Param with underline style",
+			input: "a\x1b[1;4:3m",
+			expected: []Sequence{
+				Print('a'),
+				CSI{
+					Final:        'm',
+					Parameters:   []int{1, 4},
+					Intermediate: []rune{},
+					Subparameters: [][]int{
+						{1},
+						{4, 3},
+					},
 				},
 			},
 		},
@@ -0,0 +1,106 @@
+package tcellterm
+
+import (
+	"time"
+)
+
+const (
+	// defaultFrameRate is the maximum number of EventRedraws posted per
+	// second by a VT created with New
+	defaultFrameRate = 60
+	// defaultMaxLatency is the longest a change to the screen waits for an
+	// EventRedraw in a VT created with New
+	defaultMaxLatency = 50 * time.Millisecond
+)
+
+// changed records that the screen has changed and schedules an EventRedraw.
+// Without a FrameRate, the EventRedraw is posted immediately. Otherwise it is
+// posted once the application's output goes idle, or when MaxLatency has
+// passed. During a synchronized update, the EventRedraw waits for the update
+// to end
+func (vt *VT) changed() {
+	if vt.mode&syncUpdate != 0 {
+		vt.syncChanged = true
+		return
+	}
+	if vt.dirty {
+		return
+	}
+	vt.dirty = true
+	if vt.FrameRate <= 0 {
+		vt.redrawPosted = true
+		vt.postEvent(&EventRedraw{
+			EventTerminal: newEventTerminal(vt),
+		})
+		return
+	}
+	if vt.parser == nil || vt.MaxLatency <= 0 {
+		return
+	}
+	if vt.latencyTimer != nil {
+		vt.latencyTimer.Stop()
+	}
+	vt.latencyTimer = time.AfterFunc(vt.MaxLatency, vt.requestRedraw)
+}
+
+// requestRedraw asks the goroutine reading from the application to flush a
+// pending redraw
+func (vt *VT) requestRedraw() {
+	select {
+	case vt.redraw <- struct{}{}:
+	default:
+	}
+}
+
+// flushRedraw delivers an EventRedraw if the screen has changed since the last
+// Draw, and no EventRedraw is waiting to be drawn. If limit is set, the redraw
+// is delayed until a frame has passed since the previous one
+func (vt *VT) flushRedraw(limit bool) {
+	vt.mu.Lock()
+	if !vt.dirty || vt.redrawPosted {
+		vt.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if limit && vt.FrameRate > 0 {
+		next := vt.lastRedraw.Add(time.Second / time.Duration(vt.FrameRate))
+		if now.Before(next) {
+			if vt.frameTimer == nil {
+				vt.frameTimer = time.AfterFunc(next.Sub(now), func() {
+					vt.mu.Lock()
+					vt.frameTimer = nil
+					vt.mu.Unlock()
+					vt.requestRedraw()
+				})
+			}
+			vt.mu.Unlock()
+			return
+		}
+	}
+	vt.redrawPosted = true
+	vt.lastRedraw = now
+	if vt.latencyTimer != nil {
+		vt.latencyTimer.Stop()
+		vt.latencyTimer = nil
+	}
+	handler := vt.eventHandler
+	vt.mu.Unlock()
+	handler(&EventRedraw{
+		EventTerminal: newEventTerminal(vt),
+	})
+}
+
+// redrawNow delivers an EventRedraw for a change which was not made by the
+// output of the application. If the terminal is reading from an application,
+// the EventRedraw is delivered by the goroutine reading from it
+func (vt *VT) redrawNow() {
+	vt.mu.Lock()
+	vt.dirty = true
+	running := vt.parser != nil
+	vt.mu.Unlock()
+	if running {
+		vt.requestRedraw()
+		return
+	}
+	vt.flushRedraw(false)
+}
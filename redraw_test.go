@@ -0,0 +1,106 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedraw(t *testing.T) {
+	// setup returns a VT which counts the EventRedraws delivered to it. Each
+	// EventRedraw is drawn
+	setup := func(frameRate int) (*VT, *int) {
+		vt := New()
+		vt.FrameRate = frameRate
+		vt.SetSurface(newTestSurface(10, 4))
+		vt.Resize(10, 4)
+		count := 0
+		vt.Attach(func(ev tcell.Event) {
+			if _, ok := ev.(*EventRedraw); ok {
+				count += 1
+				vt.Draw()
+			}
+		})
+		return vt, &count
+	}
+
+	t.Run("idle", func(t *testing.T) {
+		vt, count := setup(1000)
+		vt.update(Print('a'))
+		vt.flushRedraw(true)
+		assert.Equal(t, 1, *count)
+		// Nothing has changed since the last Draw
+		vt.flushRedraw(true)
+		assert.Equal(t, 1, *count)
+		vt.update(Print('b'))
+		vt.lastRedraw = time.Time{}
+		vt.flushRedraw(true)
+		assert.Equal(t, 2, *count)
+		assert.Equal(t, "ab", strings.TrimSpace(vt.String()))
+	})
+
+	t.Run("frame rate", func(t *testing.T) {
+		vt, count := setup(10)
+		// The previous frame was drawn less than a frame ago
+		vt.lastRedraw = time.Now().Add(time.Hour)
+		for i := 0; i < 50; i += 1 {
+			vt.update(Print('x'))
+			vt.flushRedraw(true)
+		}
+		assert.Equal(t, 0, *count)
+		assert.NotNil(t, vt.frameTimer)
+		vt.frameTimer.Stop()
+		vt.frameTimer = nil
+
+		// The next frame is due
+		vt.lastRedraw = time.Time{}
+		vt.flushRedraw(true)
+		assert.Equal(t, 1, *count)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		vt, count := setup(10)
+		vt.lastRedraw = time.Now().Add(time.Hour)
+		vt.update(Print('x'))
+		vt.flushRedraw(false)
+		assert.Equal(t, 1, *count)
+		assert.Nil(t, vt.frameTimer)
+	})
+
+	t.Run("request", func(t *testing.T) {
+		vt, _ := setup(10)
+		// Requests are coalesced until the reading goroutine takes one
+		vt.requestRedraw()
+		vt.requestRedraw()
+		assert.Len(t, vt.redraw, 1)
+	})
+
+	t.Run("immediate", func(t *testing.T) {
+		vt, _ := setup(0)
+		vt.update(Print('x'))
+		assert.Len(t, vt.events, 1)
+		// The screen is already waiting to be drawn
+		vt.update(Print('x'))
+		assert.Len(t, vt.events, 1)
+		_, ok := (<-vt.events).(*EventRedraw)
+		assert.True(t, ok)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		vt := New()
+		count := 0
+		vt.Attach(func(ev tcell.Event) {
+			if _, ok := ev.(*EventRedraw); ok {
+				count += 1
+			}
+		})
+		_, _ = vt.Write([]byte(strings.Repeat("x\r\n", 100)))
+		assert.Equal(t, 1, count)
+		vt.Draw()
+		_, _ = vt.Write([]byte("\x1b[H\x1b[6n"))
+		assert.Equal(t, 2, count)
+	})
+}
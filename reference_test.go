@@ -0,0 +1,208 @@
+package tcellterm
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// captures returns the paths of the reference captures in tests/
+func captures(t *testing.T) []string {
+	paths, err := filepath.Glob(filepath.Join("tests", "*"))
+	require.NoError(t, err)
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		if info.IsDir() {
+			continue
+		}
+		switch filepath.Base(path) {
+		case "README", "runner.go", "sizes.json":
+			continue
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+// goldenPath returns the path of the golden snapshot of a capture
+func goldenPath(capture string) string {
+	return filepath.Join("tests", "golden", filepath.Base(capture)+".golden")
+}
+
+// captureSize returns the size the capture at path was recorded at, from
+// tests/sizes.json
+func captureSize(t *testing.T, path string) (int, int) {
+	data, err := os.ReadFile(filepath.Join("tests", "sizes.json"))
+	require.NoError(t, err)
+	sizes := map[string]struct {
+		Columns     int `json:"columns"`
+		ScreenLines int `json:"screen_lines"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &sizes))
+	size, ok := sizes[filepath.Base(path)]
+	require.True(t, ok, "no size recorded for %s", path)
+	return size.Columns, size.ScreenLines
+}
+
+// snapshot renders the screen of the VT as text. The snapshot holds the size,
+// the cursor, the text of each row with trailing blanks removed, and the runs
+// of cells on each row which have a style other than the default
+func snapshot(vt *VT) string {
+	str := strings.Builder{}
+	fmt.Fprintf(&str, "size %dx%d\n", vt.width(), vt.height())
+	row, col, _, vis := vt.Cursor()
+	fmt.Fprintf(&str, "cursor %d,%d visible=%t\n", row, col, vis)
+
+	str.WriteString("-- text\n")
+	for _, line := range strings.Split(vt.String(), "\n") {
+		str.WriteString(strings.TrimRight(line, " "))
+		str.WriteRune('\n')
+	}
+
+	str.WriteString("-- styles\n")
+	for r, line := range vt.activeScreen {
+		for col := 0; col < len(line); {
+			style := line[col].attrs
+			ext := line[col].ext
+			end := col
+			for end+1 < len(line) && line[end+1].attrs == style && line[end+1].ext == ext {
+				end += 1
+			}
+			if style != tcell.StyleDefault || ext != (extAttrs{}) {
+				fmt.Fprintf(&str, "%d:%d-%d %s\n", r, col, end, formatStyle(style, ext))
+			}
+			col = end + 1
+		}
+	}
+	return str.String()
+}
+
+// formatStyle describes a style for a snapshot. The extended attributes are
+// described after the attributes of the style
+func formatStyle(style tcell.Style, ext extAttrs) string {
+	fg, bg, attrMask := style.Decompose()
+	fields := []string{}
+	if fg != tcell.ColorDefault {
+		fields = append(fields, "fg="+formatColor(fg))
+	}
+	if bg != tcell.ColorDefault {
+		fields = append(fields, "bg="+formatColor(bg))
+	}
+	attrs := []struct {
+		mask tcell.AttrMask
+		name string
+	}{
+		{tcell.AttrBold, "bold"},
+		{tcell.AttrDim, "dim"},
+		{tcell.AttrItalic, "italic"},
+		{tcell.AttrUnderline, "underline"},
+		{tcell.AttrBlink, "blink"},
+		{tcell.AttrReverse, "reverse"},
+		{tcell.AttrStrikeThrough, "strikethrough"},
+	}
+	for _, attr := range attrs {
+		if attrMask&attr.mask != 0 {
+			fields = append(fields, attr.name)
+		}
+	}
+	underlines := map[UnderlineStyle]string{
+		UnderlineDouble: "double",
+		UnderlineCurly:  "curly",
+		UnderlineDotted: "dotted",
+		UnderlineDashed: "dashed",
+	}
+	if name, ok := underlines[ext.underline]; ok {
+		fields = append(fields, "underline="+name)
+	}
+	if ext.underlineColor != tcell.ColorDefault {
+		fields = append(fields, "ul="+formatColor(ext.underlineColor))
+	}
+	extAttrs := []struct {
+		mask ExtAttrMask
+		name string
+	}{
+		{ExtAttrRapidBlink, "rapidblink"},
+		{ExtAttrConceal, "conceal"},
+		{ExtAttrOverline, "overline"},
+		{ExtAttrFramed, "framed"},
+		{ExtAttrEncircled, "encircled"},
+		{ExtAttrSuperscript, "superscript"},
+		{ExtAttrSubscript, "subscript"},
+	}
+	for _, attr := range extAttrs {
+		if ext.attrs&attr.mask != 0 {
+			fields = append(fields, attr.name)
+		}
+	}
+	if ext.font != 0 {
+		fields = append(fields, fmt.Sprintf("font=%d", ext.font))
+	}
+	if ext.link.url != "" {
+		fields = append(fields, "url="+ext.link.url)
+	}
+	if ext.link.id != "" {
+		fields = append(fields, "urlId="+ext.link.id)
+	}
+	return strings.Join(fields, " ")
+}
+
+// formatColor describes a color for a snapshot: RGB colors in hex, and indexed
+// colors by number
+func formatColor(c tcell.Color) string {
+	if c.IsRGB() {
+		return fmt.Sprintf("#%06x", c.Hex())
+	}
+	return strconv.Itoa(int(c - tcell.ColorValid))
+}
+
+func TestReference(t *testing.T) {
+	for _, path := range captures(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			vt := New()
+			vt.Resize(captureSize(t, path))
+			_, _ = vt.Write(data)
+			actual := snapshot(vt)
+
+			golden := goldenPath(path)
+			if *update {
+				require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+				require.NoError(t, os.WriteFile(golden, []byte(actual), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err, "no golden snapshot, run with -update to create it")
+			assert.Equal(t, string(expected), actual)
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	vt := New()
+	vt.Resize(6, 2)
+	_, _ = vt.Write([]byte("a\x1b[1;38;2;1;2;3mbc\x1b[m d\x1b[4;41me\x1b[m\r\n\x1b[7mx"))
+	expected := "size 6x2\n" +
+		"cursor 1,1 visible=true\n" +
+		"-- text\n" +
+		"abc de\n" +
+		"x\n" +
+		"-- styles\n" +
+		"0:1-2 fg=#010203 bold\n" +
+		"0:5-5 bg=1 underline\n" +
+		"1:0-0 reverse\n"
+	assert.Equal(t, expected, snapshot(vt))
+}
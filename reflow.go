@@ -0,0 +1,193 @@
+package tcellterm
+
+import "github.com/gdamore/tcell/v2"
+
+// reflow rebuilds the logical lines of the history and primary screen, and
+// rewraps them to the new width. pos is the cursor position on the primary
+// screen and pending indicates the cursor is waiting to wrap. Lines which do
+// not fit on the new screen are moved into history. The new screen, history
+// and cursor position are returned
+func (vt *VT) reflow(w int, h int, pos Position, pending bool) ([][]cell, [][]cell, Position, bool) {
+	lines, cursorLine, cursorIdx := logicalLines(vt.history, vt.primaryScreen, pos, pending)
+
+	rows := [][]cell{}
+	newPos := Position{}
+	newPending := false
+	for i, line := range lines {
+		idx := -1
+		if i == cursorLine {
+			idx = cursorIdx
+			// A cursor directly after the last character of a row
+			// is waiting to wrap, there is no need to give it a
+			// cell of its own
+			if idx == len(line) && idx > 0 {
+				_, last := rewrap(line, w, idx-1)
+				cw := line[idx-1].width
+				if cw < 1 {
+					cw = 1
+				}
+				if last.Col+cw >= w {
+					idx -= 1
+					newPending = true
+				}
+			}
+			for len(line) <= idx {
+				line = append(line, cell{})
+			}
+		}
+		wrapped, p := rewrap(line, w, idx)
+		if idx >= 0 {
+			newPos = Position{
+				Row: len(rows) + p.Row,
+				Col: p.Col,
+			}
+			if newPending {
+				newPos.Col = w - 1
+			}
+		}
+		rows = append(rows, wrapped...)
+	}
+
+	top := len(rows) - h
+	if top < 0 {
+		top = 0
+	}
+	if newPos.Row < top {
+		top = newPos.Row
+	}
+	history := rows[:top]
+	screen := make([][]cell, h)
+	for i := range screen {
+		if top+i < len(rows) {
+			screen[i] = rows[top+i]
+			continue
+		}
+		screen[i] = make([]cell, w)
+	}
+	newPos.Row -= top
+	return screen, history, newPos, newPending
+}
+
+// logicalLines joins soft-wrapped rows of the history and screen into logical
+// lines. Trailing blank cells of each line are dropped, and the second half of
+// wide characters is removed. The index of the line holding the cursor and
+// the index of the cursor within that line are also returned
+func logicalLines(history [][]cell, screen [][]cell, pos Position, pending bool) ([][]cell, int, int) {
+	// Only keep screen rows up to the last one with content, or the
+	// cursor row
+	last := pos.Row
+	for r := len(screen) - 1; r > last; r -= 1 {
+		if !blankRow(screen[r]) {
+			last = r
+			break
+		}
+	}
+	rows := make([][]cell, 0, len(history)+last+1)
+	rows = append(rows, history...)
+	rows = append(rows, screen[:last+1]...)
+	cursorRow := len(history) + pos.Row
+
+	lines := [][]cell{}
+	cursorLine := 0
+	cursorIdx := 0
+	line := []cell{}
+	for r, rw := range rows {
+		for col := 0; col < len(rw); {
+			c := rw[col]
+			cw := c.width
+			if cw < 1 {
+				cw = 1
+			}
+			if r == cursorRow && pos.Col >= col && pos.Col < col+cw {
+				cursorLine = len(lines)
+				cursorIdx = len(line)
+				if pending {
+					cursorIdx += 1
+				}
+			}
+			line = append(line, c)
+			col += cw
+		}
+		if len(rw) > 0 && rw[len(rw)-1].wrapped {
+			continue
+		}
+		for len(line) > 0 && blankCell(line[len(line)-1]) {
+			line = line[:len(line)-1]
+		}
+		lines = append(lines, line)
+		line = []cell{}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines, cursorLine, cursorIdx
+}
+
+// rewrap splits a logical line into rows of width w. The position of the cell
+// at index idx is returned
+func rewrap(line []cell, w int, idx int) ([][]cell, Position) {
+	rows := [][]cell{}
+	pos := Position{}
+	cur := make([]cell, w)
+	col := 0
+	for i, c := range line {
+		cw := c.width
+		if cw < 1 {
+			cw = 1
+		}
+		if col+cw > w && col > 0 {
+			cur[w-1].wrapped = true
+			rows = append(rows, cur)
+			cur = make([]cell, w)
+			col = 0
+		}
+		if i == idx {
+			pos = Position{Row: len(rows), Col: col}
+		}
+		c.wrapped = false
+		cur[col] = c
+		// Set trailing cells of wide characters to a space, the same as
+		// print
+		for j := 1; j < cw && col+j < w; j += 1 {
+			cur[col+j] = cell{
+				content: ' ',
+				attrs:   c.attrs,
+				ext:     c.ext,
+			}
+		}
+		col += cw
+	}
+	rows = append(rows, cur)
+	return rows, pos
+}
+
+// blankCell reports if the cell has no content and default attributes
+func blankCell(c cell) bool {
+	if c.content != 0 && c.content != ' ' {
+		return false
+	}
+	return len(c.combining) == 0 && c.attrs == tcell.StyleDefault && c.ext == extAttrs{}
+}
+
+// blankRow reports if every cell in the row is blank
+func blankRow(rw []cell) bool {
+	for _, c := range rw {
+		if !blankCell(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// resizeGrid truncates or pads grid to the new size, keeping the content
+// anchored to the top left
+func resizeGrid(grid [][]cell, w int, h int) [][]cell {
+	resized := make([][]cell, h)
+	for i := range resized {
+		resized[i] = make([]cell, w)
+		if i < len(grid) {
+			copy(resized[i], grid[i])
+		}
+	}
+	return resized
+}
@@ -0,0 +1,141 @@
+package tcellterm
+
+// defaultScrollback is the number of lines of history kept when a VT is
+// created with New
+const defaultScrollback = 3000
+
+// pushHistory copies the top n rows of the primary screen into the scrollback
+// history. History is only recorded when the primary screen scrolls with the
+// top margin at the first row
+func (vt *VT) pushHistory(n int) {
+	if !vt.recordsHistory() {
+		return
+	}
+	pushed := 0
+	for r := 0; r < n && r <= int(vt.margin.bottom); r += 1 {
+		line := make([]cell, len(vt.activeScreen[r]))
+		copy(line, vt.activeScreen[r])
+		vt.history = append(vt.history, line)
+		pushed += 1
+	}
+	// Keep the view anchored to the same content if the user has scrolled
+	// back
+	if vt.viewOffset > 0 {
+		vt.viewOffset += pushed
+		vt.damageAll()
+	}
+	vt.trimHistory()
+}
+
+// recordsHistory reports if rows scrolled off the top of the screen will be
+// saved to the history. Only whole rows are saved, so history is not recorded
+// while the left and right margins are set
+func (vt *VT) recordsHistory() bool {
+	if vt.Scrollback <= 0 {
+		return false
+	}
+	if vt.mode&smcup != 0 {
+		return false
+	}
+	return vt.margin.top == 0 && vt.fullWidth()
+}
+
+// trimHistory drops the oldest lines of history until it fits within the
+// configured scrollback
+func (vt *VT) trimHistory() {
+	max := vt.Scrollback
+	if max < 0 {
+		max = 0
+	}
+	if over := len(vt.history) - max; over > 0 {
+		vt.history = vt.history[over:]
+	}
+	if vt.viewOffset > len(vt.history) {
+		vt.viewOffset = len(vt.history)
+	}
+	if vt.selection.anchor.Row < -len(vt.history) || vt.selection.end.Row < -len(vt.history) {
+		vt.clearSelection()
+	}
+	vt.trimHighlights()
+}
+
+// clearHistory erases all lines of scrollback history
+func (vt *VT) clearHistory() {
+	if vt.offset() > 0 {
+		vt.damageAll()
+	}
+	vt.history = nil
+	vt.viewOffset = 0
+	vt.trimHighlights()
+	if vt.selection.anchor.Row < 0 || vt.selection.end.Row < 0 {
+		vt.clearSelection()
+	}
+}
+
+// viewRow returns the line which is displayed at row y of the view, taking
+// into account how far the view is scrolled back. The returned line may be
+// shorter or longer than the current width if it was recorded at a different
+// size
+func (vt *VT) viewRow(y int) []cell {
+	offset := vt.offset()
+	if y < offset {
+		return vt.history[len(vt.history)-offset+y]
+	}
+	return vt.activeScreen[y-offset]
+}
+
+// offset returns the effective view offset. The alternate screen has no
+// history, so it is always drawn at the bottom
+func (vt *VT) offset() int {
+	if vt.mode&smcup != 0 {
+		return 0
+	}
+	return vt.viewOffset
+}
+
+// ScrollViewUp scrolls the view n lines back into the scrollback history. The
+// view does not scroll while the alternate screen is active
+func (vt *VT) ScrollViewUp(n int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.mode&smcup != 0 {
+		return
+	}
+	vt.scrollView(n)
+}
+
+// ScrollViewDown scrolls the view n lines toward the bottom of the screen
+func (vt *VT) ScrollViewDown(n int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.scrollView(-n)
+}
+
+// scrollView moves the view n lines back into the history, keeping it between
+// the live screen and the oldest line of history
+func (vt *VT) scrollView(n int) {
+	vt.viewOffset += n
+	if vt.viewOffset > len(vt.history) {
+		vt.viewOffset = len(vt.history)
+	}
+	if vt.viewOffset < 0 {
+		vt.viewOffset = 0
+	}
+	vt.damageAll()
+}
+
+// ScrollViewToBottom returns the view to the live screen
+func (vt *VT) ScrollViewToBottom() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.viewOffset = 0
+	vt.damageAll()
+}
+
+// ViewOffset returns the number of lines the view is scrolled back into the
+// history. A value of 0 means the live screen is displayed
+func (vt *VT) ViewOffset() int {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	return vt.offset()
+}
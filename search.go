@@ -0,0 +1,256 @@
+package tcellterm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Position is a cell in grid coordinates. Row 0 is the top row of the screen,
+// and negative rows are lines of scrollback history, with -1 being the most
+// recent
+type Position struct {
+	Row int
+	Col int
+}
+
+// before reports if p comes before other, reading left to right and top to
+// bottom
+func (p Position) before(other Position) bool {
+	if p.Row != other.Row {
+		return p.Row < other.Row
+	}
+	return p.Col < other.Col
+}
+
+// Match is a range of cells matching a search. Start is the first cell of the
+// match and End is the last
+type Match struct {
+	Start Position
+	End   Position
+}
+
+// SearchOptions control how VT.Search finds matches
+type SearchOptions struct {
+	// Regexp interprets the pattern as a regular expression. Otherwise the
+	// pattern is matched literally
+	Regexp bool
+	// IgnoreCase matches without regard to case
+	IgnoreCase bool
+	// Backward returns matches from the bottom of the screen toward the
+	// top of the history
+	Backward bool
+	// From, if set, limits the matches to those starting after From, or
+	// before From when searching backward
+	From *Position
+}
+
+// defaultHighlightStyle is the style used to draw highlighted matches
+var defaultHighlightStyle = tcell.StyleDefault.
+	Foreground(tcell.ColorBlack).
+	Background(tcell.ColorYellow)
+
+// Search finds all matches of pattern in the history and the screen. Matches
+// may span soft-wrapped rows. The matches are returned in the order of the
+// search direction
+func (vt *VT) Search(pattern string, opts SearchOptions) ([]Match, error) {
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	matches := []Match{}
+	for _, line := range vt.searchLines() {
+		for _, loc := range re.FindAllStringIndex(line.text, -1) {
+			if loc[0] == loc[1] {
+				// Skip empty matches
+				continue
+			}
+			m := Match{
+				Start: line.positionAt(loc[0]),
+				End:   line.positionAt(loc[1] - 1),
+			}
+			if opts.From != nil {
+				if !opts.Backward && !opts.From.before(m.Start) {
+					continue
+				}
+				if opts.Backward && !m.Start.before(*opts.From) {
+					continue
+				}
+			}
+			matches = append(matches, m)
+		}
+	}
+	if opts.Backward {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[j].Start.before(matches[i].Start)
+		})
+	}
+	return matches, nil
+}
+
+// Highlight draws the matches with the VT's HighlightStyle. Any previous
+// highlights are replaced
+func (vt *VT) Highlight(matches []Match) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	highlights := make([]Match, len(matches))
+	copy(highlights, matches)
+	vt.setHighlights(highlights)
+	vt.damageAll()
+}
+
+// ClearHighlight removes all highlighted matches
+func (vt *VT) ClearHighlight() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.setHighlights(nil)
+	vt.damageAll()
+}
+
+// setHighlights replaces the highlighted matches and indexes them by row
+func (vt *VT) setHighlights(matches []Match) {
+	vt.highlights = matches
+	vt.highlightRows = nil
+	if len(matches) == 0 {
+		return
+	}
+	vt.highlightRows = make(map[int][]Match)
+	for _, m := range matches {
+		for r := m.Start.Row; r <= m.End.Row; r += 1 {
+			vt.highlightRows[r] = append(vt.highlightRows[r], m)
+		}
+	}
+}
+
+// highlighted reports if the cell at grid position r, col is within a
+// highlighted match
+func (vt *VT) highlighted(r int, col int) bool {
+	for _, m := range vt.highlightRows[r] {
+		if r == m.Start.Row && col < m.Start.Col {
+			continue
+		}
+		if r == m.End.Row && col > m.End.Col {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// scrollHighlights moves highlighted matches along with content scrolled by n
+// rows between the top and bottom rows of the screen, in the same way as
+// scrollSelection. Matches which are partially inside the scrolled region, or
+// scrolled off of it, are dropped
+func (vt *VT) scrollHighlights(top int, bottom int, n int, history bool) {
+	if len(vt.highlights) == 0 {
+		return
+	}
+	lower := top
+	if history {
+		lower = -len(vt.history)
+	}
+	kept := make([]Match, 0, len(vt.highlights))
+	for _, m := range vt.highlights {
+		switch {
+		case m.End.Row < lower || m.Start.Row > bottom:
+			// Entirely outside the scrolled region
+			kept = append(kept, m)
+			continue
+		case m.Start.Row < lower || m.End.Row > bottom:
+			continue
+		}
+		m.Start.Row -= n
+		m.End.Row -= n
+		if m.End.Row > bottom || (!history && m.Start.Row < top) {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	vt.setHighlights(kept)
+}
+
+// trimHighlights drops highlighted matches which start in lines no longer in
+// the history
+func (vt *VT) trimHighlights() {
+	if len(vt.highlights) == 0 {
+		return
+	}
+	kept := make([]Match, 0, len(vt.highlights))
+	for _, m := range vt.highlights {
+		if m.Start.Row < -len(vt.history) {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	vt.setHighlights(kept)
+}
+
+// searchLine is a logical line of text, with the grid position of each
+// character
+type searchLine struct {
+	text string
+	// offsets holds the byte offset in text at which each cell starts
+	offsets []int
+	cells   []Position
+}
+
+// positionAt returns the position of the cell containing the byte at index i
+// of the text
+func (l searchLine) positionAt(i int) Position {
+	n := sort.Search(len(l.offsets), func(j int) bool {
+		return l.offsets[j] > i
+	})
+	return l.cells[n-1]
+}
+
+// searchLines joins the rows of the history and screen into logical lines,
+// following soft wraps. Trailing blanks are dropped
+func (vt *VT) searchLines() []searchLine {
+	lines := []searchLine{}
+	text := strings.Builder{}
+	line := searchLine{}
+	top := 0
+	if vt.mode&smcup == 0 {
+		top = -len(vt.history)
+	}
+	for r := top; r < vt.height(); r += 1 {
+		rw := vt.gridRow(r)
+		for col := 0; col < len(rw); {
+			cell := rw[col]
+			line.offsets = append(line.offsets, text.Len())
+			line.cells = append(line.cells, Position{Row: r, Col: col})
+			text.WriteRune(cell.rune())
+			for _, comb := range cell.combining {
+				text.WriteRune(comb)
+			}
+			w := cell.width
+			if w < 1 {
+				w = 1
+			}
+			col += w
+		}
+		if len(rw) > 0 && rw[len(rw)-1].wrapped {
+			continue
+		}
+		line.text = strings.TrimRight(text.String(), " ")
+		lines = append(lines, line)
+		text.Reset()
+		line = searchLine{}
+	}
+	if text.Len() > 0 {
+		line.text = strings.TrimRight(text.String(), " ")
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,147 @@
+package tcellterm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	setup := func() *VT {
+		vt := New()
+		vt.Resize(6, 3)
+		_, _ = vt.Write([]byte("error1\r\nok\r\nError2 err\r\naつb"))
+		return vt
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		opts     SearchOptions
+		expected []Match
+	}{
+		{
+			name:    "literal",
+			pattern: "err",
+			expected: []Match{
+				{Start: Position{Row: -2, Col: 0}, End: Position{Row: -2, Col: 2}},
+				{Start: Position{Row: 1, Col: 1}, End: Position{Row: 1, Col: 3}},
+			},
+		},
+		{
+			name:    "ignore case",
+			pattern: "ERROR",
+			opts:    SearchOptions{IgnoreCase: true},
+			expected: []Match{
+				{Start: Position{Row: -2, Col: 0}, End: Position{Row: -2, Col: 4}},
+				{Start: Position{Row: 0, Col: 0}, End: Position{Row: 0, Col: 4}},
+			},
+		},
+		{
+			name:    "across soft wrap",
+			pattern: "2 e",
+			expected: []Match{
+				{Start: Position{Row: 0, Col: 5}, End: Position{Row: 1, Col: 1}},
+			},
+		},
+		{
+			name:    "regexp",
+			pattern: `[Ee]rror\d`,
+			opts:    SearchOptions{Regexp: true},
+			expected: []Match{
+				{Start: Position{Row: -2, Col: 0}, End: Position{Row: -2, Col: 5}},
+				{Start: Position{Row: 0, Col: 0}, End: Position{Row: 0, Col: 5}},
+			},
+		},
+		{
+			name:    "backward from",
+			pattern: "rr",
+			opts: SearchOptions{
+				Backward: true,
+				From:     &Position{Row: 1, Col: 2},
+			},
+			expected: []Match{
+				{Start: Position{Row: 0, Col: 1}, End: Position{Row: 0, Col: 2}},
+				{Start: Position{Row: -2, Col: 1}, End: Position{Row: -2, Col: 2}},
+			},
+		},
+		{
+			name:    "forward from",
+			pattern: "rr",
+			opts: SearchOptions{
+				From: &Position{Row: -2, Col: 1},
+			},
+			expected: []Match{
+				{Start: Position{Row: 0, Col: 1}, End: Position{Row: 0, Col: 2}},
+				{Start: Position{Row: 1, Col: 2}, End: Position{Row: 1, Col: 3}},
+			},
+		},
+		{
+			name:    "wide characters",
+			pattern: "つb",
+			expected: []Match{
+				{Start: Position{Row: 2, Col: 1}, End: Position{Row: 2, Col: 3}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := setup()
+			actual, err := vt.Search(test.pattern, test.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	t.Run("invalid regexp", func(t *testing.T) {
+		vt := setup()
+		_, err := vt.Search("(", SearchOptions{Regexp: true})
+		assert.Error(t, err)
+	})
+
+	t.Run("highlight scrolls with history", func(t *testing.T) {
+		vt := setup()
+		matches, _ := vt.Search("つ", SearchOptions{})
+		vt.Highlight(matches)
+		assert.True(t, vt.highlighted(2, 1))
+		vt.nel()
+		assert.True(t, vt.highlighted(1, 1))
+		vt.ClearHighlight()
+		assert.False(t, vt.highlighted(1, 1))
+	})
+
+	t.Run("highlight scrolls down", func(t *testing.T) {
+		vt := setup()
+		matches, _ := vt.Search("Error", SearchOptions{})
+		vt.Highlight(matches)
+		vt.scrollDown(1)
+		assert.False(t, vt.highlighted(0, 0))
+		assert.True(t, vt.highlighted(1, 0))
+	})
+
+	t.Run("highlight scrolls in region", func(t *testing.T) {
+		vt := setup()
+		matches, _ := vt.Search("Error|つ", SearchOptions{Regexp: true})
+		vt.Highlight(matches)
+		vt.decstbm([]int{2, 3})
+		vt.scrollUp(1)
+		assert.True(t, vt.highlighted(0, 0))
+		assert.True(t, vt.highlighted(1, 1))
+		assert.False(t, vt.highlighted(2, 1))
+	})
+
+	t.Run("highlight follows inserted and deleted lines", func(t *testing.T) {
+		vt := setup()
+		matches, _ := vt.Search("Error|つ", SearchOptions{Regexp: true})
+		vt.Highlight(matches)
+		vt.cursor.row = 0
+		vt.il(1)
+		assert.False(t, vt.highlighted(0, 0))
+		assert.True(t, vt.highlighted(1, 0))
+		assert.False(t, vt.highlighted(2, 1))
+		vt.dl(1)
+		assert.True(t, vt.highlighted(0, 0))
+		vt.dl(1)
+		assert.False(t, vt.highlighted(0, 0))
+	})
+}
@@ -0,0 +1,319 @@
+package tcellterm
+
+import (
+	"strings"
+)
+
+// SelectionMode determines which cells are selected between the start and end
+// of a selection
+type SelectionMode int
+
+const (
+	// SelectCharacter selects every cell between the start and end of the
+	// selection
+	SelectCharacter SelectionMode = iota
+	// SelectWord extends the selection to whole words. Words are bounded
+	// by the VT's WordDelimiters
+	SelectWord
+	// SelectLine extends the selection to whole lines, including any
+	// soft-wrapped rows
+	SelectLine
+	// SelectBlock selects a rectangle of cells with the start and end of
+	// the selection at opposite corners
+	SelectBlock
+)
+
+// defaultWordDelimiters are the runes which separate words when selecting in
+// SelectWord mode
+const defaultWordDelimiters = " \t,│`|:\"'()[]{}<>"
+
+// selection is a selected region of the active screen. Positions are in grid
+// coordinates: row 0 is the top of the screen, and negative rows are lines of
+// history
+type selection struct {
+	active bool
+	mode   SelectionMode
+	anchor Position
+	end    Position
+}
+
+// SelectionStart begins a new selection at the cell x, y of the view
+func (vt *VT) SelectionStart(x int, y int, mode SelectionMode) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	pos := vt.viewToGrid(x, y)
+	vt.selection = selection{
+		active: true,
+		mode:   mode,
+		anchor: pos,
+		end:    pos,
+	}
+	vt.damageAll()
+}
+
+// SelectionExtend moves the end of the current selection to the cell x, y of
+// the view
+func (vt *VT) SelectionExtend(x int, y int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if !vt.selection.active {
+		return
+	}
+	vt.selection.end = vt.viewToGrid(x, y)
+	vt.damageAll()
+}
+
+// SelectionClear removes the current selection
+func (vt *VT) SelectionClear() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.clearSelection()
+}
+
+// SelectedText returns the text of the current selection. Soft-wrapped rows are
+// joined, and trailing blanks of each line are dropped
+func (vt *VT) SelectedText() string {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if !vt.selection.active {
+		return ""
+	}
+	start, end := vt.selectionBounds()
+	left, right := start.Col, end.Col
+	if left > right {
+		left, right = right, left
+	}
+
+	str := strings.Builder{}
+	for r := start.Row; r <= end.Row; r += 1 {
+		line := vt.gridRow(r)
+		c0, c1 := 0, len(line)-1
+		switch {
+		case vt.selection.mode == SelectBlock:
+			c0, c1 = left, right
+		default:
+			if r == start.Row {
+				c0 = start.Col
+			}
+			if r == end.Row {
+				c1 = end.Col
+			}
+		}
+		text := strings.Builder{}
+		for col := 0; col < len(line); {
+			cell := line[col]
+			w := cell.width
+			if w < 1 {
+				w = 1
+			}
+			if col+w-1 >= c0 && col <= c1 {
+				text.WriteRune(cell.rune())
+				for _, comb := range cell.combining {
+					text.WriteRune(comb)
+				}
+			}
+			col += w
+		}
+		soft := vt.selection.mode != SelectBlock && len(line) > 0 && line[len(line)-1].wrapped
+		switch {
+		case soft && r < end.Row:
+			str.WriteString(text.String())
+		case r < end.Row:
+			str.WriteString(strings.TrimRight(text.String(), " "))
+			str.WriteRune('\n')
+		default:
+			str.WriteString(strings.TrimRight(text.String(), " "))
+		}
+	}
+	return str.String()
+}
+
+// clearSelection removes the current selection
+func (vt *VT) clearSelection() {
+	if vt.selection.active {
+		vt.damageAll()
+	}
+	vt.selection = selection{}
+}
+
+// selected reports if the cell at grid position r, col is within the selection
+func (vt *VT) selected(r int, col int, start Position, end Position) bool {
+	if !vt.selection.active {
+		return false
+	}
+	if r < start.Row || r > end.Row {
+		return false
+	}
+	if vt.selection.mode == SelectBlock {
+		left, right := start.Col, end.Col
+		if left > right {
+			left, right = right, left
+		}
+		return col >= left && col <= right
+	}
+	if r == start.Row && col < start.Col {
+		return false
+	}
+	if r == end.Row && col > end.Col {
+		return false
+	}
+	return true
+}
+
+// selectionBounds returns the start and end of the selection, in order and
+// expanded according to the selection mode
+func (vt *VT) selectionBounds() (Position, Position) {
+	start, end := vt.selection.anchor, vt.selection.end
+	if end.Row < start.Row || (end.Row == start.Row && end.Col < start.Col) {
+		start, end = end, start
+	}
+	switch vt.selection.mode {
+	case SelectBlock:
+		// Block selections keep the columns of their corners
+		if start.Row > end.Row {
+			start.Row, end.Row = end.Row, start.Row
+		}
+	case SelectWord:
+		word := vt.isWordCell(start)
+		for {
+			prev, ok := vt.prevCell(start)
+			if !ok || vt.isWordCell(prev) != word {
+				break
+			}
+			start = prev
+		}
+		word = vt.isWordCell(end)
+		for {
+			next, ok := vt.nextCell(end)
+			if !ok || vt.isWordCell(next) != word {
+				break
+			}
+			end = next
+		}
+	case SelectLine:
+		for {
+			prev := vt.gridRow(start.Row - 1)
+			if len(prev) == 0 || !prev[len(prev)-1].wrapped {
+				break
+			}
+			start.Row -= 1
+		}
+		start.Col = 0
+		for {
+			line := vt.gridRow(end.Row)
+			if len(line) == 0 || !line[len(line)-1].wrapped || vt.gridRow(end.Row+1) == nil {
+				break
+			}
+			end.Row += 1
+		}
+		end.Col = len(vt.gridRow(end.Row)) - 1
+	}
+	return start, end
+}
+
+// isWordCell reports if the cell at the position is part of a word. The second
+// half of a wide character belongs to the same class as the first
+func (vt *VT) isWordCell(pos Position) bool {
+	line := vt.gridRow(pos.Row)
+	if pos.Col < 0 || pos.Col >= len(line) {
+		return false
+	}
+	cell := line[pos.Col]
+	if pos.Col > 0 && line[pos.Col-1].width > 1 {
+		cell = line[pos.Col-1]
+	}
+	return !strings.ContainsRune(vt.WordDelimiters, cell.rune())
+}
+
+// prevCell returns the position before pos, following soft-wrapped rows
+func (vt *VT) prevCell(pos Position) (Position, bool) {
+	if pos.Col > 0 {
+		return Position{Row: pos.Row, Col: pos.Col - 1}, true
+	}
+	prev := vt.gridRow(pos.Row - 1)
+	if len(prev) == 0 || !prev[len(prev)-1].wrapped {
+		return pos, false
+	}
+	return Position{Row: pos.Row - 1, Col: len(prev) - 1}, true
+}
+
+// nextCell returns the position after pos, following soft-wrapped rows
+func (vt *VT) nextCell(pos Position) (Position, bool) {
+	line := vt.gridRow(pos.Row)
+	if pos.Col < len(line)-1 {
+		return Position{Row: pos.Row, Col: pos.Col + 1}, true
+	}
+	if len(line) == 0 || !line[len(line)-1].wrapped || vt.gridRow(pos.Row+1) == nil {
+		return pos, false
+	}
+	return Position{Row: pos.Row + 1, Col: 0}, true
+}
+
+// gridRow returns the line at row r in grid coordinates, or nil if there is no
+// such row. Negative rows are lines of history, with -1 being the most recent
+func (vt *VT) gridRow(r int) []cell {
+	switch {
+	case r >= vt.height():
+		return nil
+	case r >= 0:
+		return vt.activeScreen[r]
+	case vt.mode&smcup != 0:
+		return nil
+	case -r > len(vt.history):
+		return nil
+	default:
+		return vt.history[len(vt.history)+r]
+	}
+}
+
+// viewToGrid converts a cell of the view to grid coordinates, clamping it to
+// the view
+func (vt *VT) viewToGrid(x int, y int) Position {
+	if x < 0 {
+		x = 0
+	}
+	if x > vt.width()-1 {
+		x = vt.width() - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	if y > vt.height()-1 {
+		y = vt.height() - 1
+	}
+	return Position{Row: y - vt.offset(), Col: x}
+}
+
+// scrollSelection moves the selection along with content scrolled by n rows
+// between the top and bottom rows of the screen. Positive n moves content up.
+// If history is true, rows scrolled off the top go into the history and the
+// selection may follow them. A selection which is partially inside the scrolled
+// region, or scrolled off of it, is cleared
+func (vt *VT) scrollSelection(top int, bottom int, n int, history bool) {
+	if !vt.selection.active {
+		return
+	}
+	first, last := vt.selection.anchor.Row, vt.selection.end.Row
+	if first > last {
+		first, last = last, first
+	}
+	lower := top
+	if history {
+		lower = -len(vt.history)
+	}
+	switch {
+	case last < lower || first > bottom:
+		// Entirely outside the scrolled region
+		return
+	case first < lower || last > bottom:
+		vt.clearSelection()
+		return
+	}
+	vt.selection.anchor.Row -= n
+	vt.selection.end.Row -= n
+	first -= n
+	last -= n
+	if last > bottom || (!history && first < top) {
+		vt.clearSelection()
+	}
+}
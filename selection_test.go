@@ -0,0 +1,159 @@
+package tcellterm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelection(t *testing.T) {
+	setup := func(lines ...string) *VT {
+		vt := New()
+		vt.Resize(6, 3)
+		for i, line := range lines {
+			if i > 0 {
+				vt.nel()
+			}
+			for _, r := range line {
+				vt.print(r)
+			}
+		}
+		return vt
+	}
+
+	tests := []struct {
+		name     string
+		lines    []string
+		mode     SelectionMode
+		start    Position
+		end      Position
+		expected string
+	}{
+		{
+			name:     "character",
+			lines:    []string{"hello", "world"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 0, Col: 1},
+			end:      Position{Row: 1, Col: 2},
+			expected: "ello\nwor",
+		},
+		{
+			name:     "character reversed",
+			lines:    []string{"hello", "world"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 1, Col: 2},
+			end:      Position{Row: 0, Col: 1},
+			expected: "ello\nwor",
+		},
+		{
+			name:     "trailing blanks",
+			lines:    []string{"ab", "cd"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 0, Col: 0},
+			end:      Position{Row: 1, Col: 5},
+			expected: "ab\ncd",
+		},
+		{
+			name:     "soft wrap",
+			lines:    []string{"abcdefgh"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 0, Col: 4},
+			end:      Position{Row: 1, Col: 1},
+			expected: "efgh",
+		},
+		{
+			name:     "word",
+			lines:    []string{"ab cd", "ef"},
+			mode:     SelectWord,
+			start:    Position{Row: 0, Col: 4},
+			end:      Position{Row: 0, Col: 4},
+			expected: "cd",
+		},
+		{
+			name:     "word across wrap",
+			lines:    []string{"a bcdefg"},
+			mode:     SelectWord,
+			start:    Position{Row: 1, Col: 0},
+			end:      Position{Row: 1, Col: 0},
+			expected: "bcdefg",
+		},
+		{
+			name:     "line",
+			lines:    []string{"abcdefgh", "ij"},
+			mode:     SelectLine,
+			start:    Position{Row: 1, Col: 0},
+			end:      Position{Row: 1, Col: 0},
+			expected: "abcdefgh",
+		},
+		{
+			name:     "block",
+			lines:    []string{"abcd", "efgh", "ijkl"},
+			mode:     SelectBlock,
+			start:    Position{Row: 0, Col: 2},
+			end:      Position{Row: 2, Col: 1},
+			expected: "bc\nfg\njk",
+		},
+		{
+			name:     "combining",
+			lines:    []string{"e\u0301x"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 0, Col: 0},
+			end:      Position{Row: 0, Col: 0},
+			expected: "e\u0301",
+		},
+		{
+			name:     "wide",
+			lines:    []string{"aつb"},
+			mode:     SelectCharacter,
+			start:    Position{Row: 0, Col: 2},
+			end:      Position{Row: 0, Col: 3},
+			expected: "つb",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := setup(test.lines...)
+			vt.SelectionStart(test.start.Col, test.start.Row, test.mode)
+			vt.SelectionExtend(test.end.Col, test.end.Row)
+			assert.Equal(t, test.expected, vt.SelectedText())
+		})
+	}
+
+	t.Run("clear", func(t *testing.T) {
+		vt := setup("abc")
+		vt.SelectionStart(0, 0, SelectCharacter)
+		vt.SelectionExtend(2, 0)
+		vt.SelectionClear()
+		assert.Equal(t, "", vt.SelectedText())
+	})
+
+	t.Run("scroll into history", func(t *testing.T) {
+		vt := setup("ab", "cd", "ef")
+		vt.SelectionStart(0, 1, SelectCharacter)
+		vt.SelectionExtend(1, 1)
+		vt.nel()
+		assert.Equal(t, Position{Row: 0, Col: 0}, vt.selection.anchor)
+		assert.Equal(t, "cd", vt.SelectedText())
+		vt.nel()
+		assert.Equal(t, Position{Row: -1, Col: 0}, vt.selection.anchor)
+		assert.Equal(t, "cd", vt.SelectedText())
+	})
+
+	t.Run("scroll region", func(t *testing.T) {
+		vt := setup("ab", "cd", "ef")
+		vt.SelectionStart(0, 0, SelectCharacter)
+		vt.SelectionExtend(1, 1)
+		vt.decstbm([]int{2, 3})
+		vt.scrollUp(1)
+		assert.False(t, vt.selection.active)
+	})
+
+	t.Run("scrolled view", func(t *testing.T) {
+		vt := setup("ab", "cd", "ef")
+		vt.nel()
+		vt.ScrollViewUp(1)
+		vt.SelectionStart(0, 0, SelectCharacter)
+		vt.SelectionExtend(1, 0)
+		assert.Equal(t, "ab", vt.SelectedText())
+	})
+}
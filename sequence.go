@@ -38,6 +38,11 @@ type CSI struct {
 	Final        rune
 	Intermediate []rune
 	Parameters   []int
+	// Subparameters holds the values of each parameter when any parameter
+	// has colon separated subparameters, one entry per parameter. CSI 4:3 m
+	// has Subparameters [][]int{{4, 3}}. Subparameters is nil when no
+	// parameter has subparameters
+	Subparameters [][]int
 }
 
 func (seq CSI) String() string {
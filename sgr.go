@@ -10,6 +10,7 @@ func (vt *VT) sgr(params []int) {
 		switch params[i] {
 		case 0:
 			vt.cursor.attrs = tcell.StyleDefault
+			vt.cursor.ext = extAttrs{}
 		case 1:
 			vt.cursor.attrs = vt.cursor.attrs.Bold(true)
 		case 2:
@@ -17,62 +18,50 @@ func (vt *VT) sgr(params []int) {
 		case 3:
 			vt.cursor.attrs = vt.cursor.attrs.Italic(true)
 		case 4:
-			vt.cursor.attrs = vt.cursor.attrs.Underline(true)
+			vt.setUnderline(UnderlineSingle)
 		case 5:
 			vt.cursor.attrs = vt.cursor.attrs.Blink(true)
+			vt.cursor.ext.attrs &^= ExtAttrRapidBlink
+		case 6:
+			vt.cursor.attrs = vt.cursor.attrs.Blink(true)
+			vt.cursor.ext.attrs |= ExtAttrRapidBlink
 		case 7:
 			vt.cursor.attrs = vt.cursor.attrs.Reverse(true)
 		case 8:
-			// Invisible, not supported
+			vt.cursor.ext.attrs |= ExtAttrConceal
 		case 9:
 			vt.cursor.attrs = vt.cursor.attrs.StrikeThrough(true)
+		case 10:
+			vt.cursor.ext.font = 0
+		case 11, 12, 13, 14, 15, 16, 17, 18, 19:
+			vt.cursor.ext.font = params[i] - 10
 		case 21:
-			// Double underlined, not supported
+			vt.setUnderline(UnderlineDouble)
 		case 22:
 			vt.cursor.attrs = vt.cursor.attrs.Bold(false).Dim(false)
 		case 23:
 			vt.cursor.attrs = vt.cursor.attrs.Italic(false)
 		case 24:
-			vt.cursor.attrs = vt.cursor.attrs.Underline(false)
+			vt.setUnderline(UnderlineNone)
 		case 25:
 			vt.cursor.attrs = vt.cursor.attrs.Blink(false)
+			vt.cursor.ext.attrs &^= ExtAttrRapidBlink
 		case 27:
 			vt.cursor.attrs = vt.cursor.attrs.Reverse(false)
 		case 28:
-			// Not invisible, not supported
+			vt.cursor.ext.attrs &^= ExtAttrConceal
 		case 29:
 			vt.cursor.attrs = vt.cursor.attrs.StrikeThrough(false)
 		case 30, 31, 32, 33, 34, 35, 36, 37:
 			color := tcell.PaletteColor(params[i] - 30)
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
 		case 38:
-			var color tcell.Color
-			if len(params[i:]) < 3 {
-				// Malformed without at least 3 params. Don't
-				// set any more attributes at this point
-				return
-			}
-			switch params[i+1] {
-			case 2:
-				if len(params[i:]) < 5 {
-					// Malformed without at least5 params.
-					// Don't set any more attributes at this
-					// point
-					return
-				}
-				color = tcell.NewRGBColor(
-					int32(params[i+2]),
-					int32(params[i+3]),
-					int32(params[i+4]),
-				)
-				i += 4
-			case 5:
-				color = tcell.PaletteColor(params[i+2])
-				i += 2
-			default:
-				// Malformed
+			color, n, ok := sgrColor(params[i:])
+			if !ok {
+				// Don't set any more attributes at this point
 				return
 			}
+			i += n
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
 		case 39:
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(tcell.ColorDefault)
@@ -80,36 +69,45 @@ func (vt *VT) sgr(params []int) {
 			color := tcell.PaletteColor(params[i] - 40)
 			vt.cursor.attrs = vt.cursor.attrs.Background(color)
 		case 48:
-			var color tcell.Color
-			if len(params[i:]) < 3 {
-				// Malformed without at least 3 params. Don't
-				// set any more attributes at this point
-				return
-			}
-			switch params[i+1] {
-			case 2:
-				if len(params[i:]) < 5 {
-					// Malformed without at least5 params.
-					// Don't set any more attributes at this
-					// point
-					return
-				}
-				color = tcell.NewRGBColor(
-					int32(params[i+2]),
-					int32(params[i+3]),
-					int32(params[i+4]),
-				)
-				i += 4
-			case 5:
-				color = tcell.PaletteColor(params[i+2])
-				i += 2
-			default:
-				// Malformed
+			color, n, ok := sgrColor(params[i:])
+			if !ok {
+				// Don't set any more attributes at this point
 				return
 			}
+			i += n
 			vt.cursor.attrs = vt.cursor.attrs.Background(color)
 		case 49:
 			vt.cursor.attrs = vt.cursor.attrs.Background(tcell.ColorDefault)
+		case 51:
+			vt.cursor.ext.attrs &^= ExtAttrEncircled
+			vt.cursor.ext.attrs |= ExtAttrFramed
+		case 52:
+			vt.cursor.ext.attrs &^= ExtAttrFramed
+			vt.cursor.ext.attrs |= ExtAttrEncircled
+		case 53:
+			vt.cursor.ext.attrs |= ExtAttrOverline
+		case 54:
+			vt.cursor.ext.attrs &^= ExtAttrFramed | ExtAttrEncircled
+		case 55:
+			vt.cursor.ext.attrs &^= ExtAttrOverline
+		case 58:
+			color, n, ok := sgrColor(params[i:])
+			if !ok {
+				// Don't set any more attributes at this point
+				return
+			}
+			i += n
+			vt.cursor.ext.underlineColor = color
+		case 59:
+			vt.cursor.ext.underlineColor = tcell.ColorDefault
+		case 73:
+			vt.cursor.ext.attrs &^= ExtAttrSubscript
+			vt.cursor.ext.attrs |= ExtAttrSuperscript
+		case 74:
+			vt.cursor.ext.attrs &^= ExtAttrSuperscript
+			vt.cursor.ext.attrs |= ExtAttrSubscript
+		case 75:
+			vt.cursor.ext.attrs &^= ExtAttrSuperscript | ExtAttrSubscript
 		case 90, 91, 92, 93, 94, 95, 96, 97:
 			color := tcell.PaletteColor(params[i] - 90 + 8)
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
@@ -119,3 +117,87 @@ func (vt *VT) sgr(params []int) {
 		}
 	}
 }
+
+// sgrColor parses an extended color, SGR 38, 48 or 58 followed by 5;n for a
+// palette color or 2;r;g;b for an RGB color. It returns the color and the
+// number of parameters following params[0] which were used. ok is false if
+// the color is malformed
+func sgrColor(params []int) (color tcell.Color, n int, ok bool) {
+	if len(params) < 3 {
+		// Malformed without at least 3 params
+		return 0, 0, false
+	}
+	switch params[1] {
+	case 2:
+		if len(params) < 5 {
+			// Malformed without at least 5 params
+			return 0, 0, false
+		}
+		color = tcell.NewRGBColor(
+			int32(params[2]),
+			int32(params[3]),
+			int32(params[4]),
+		)
+		return color, 4, true
+	case 5:
+		return tcell.PaletteColor(params[2]), 2, true
+	}
+	return 0, 0, false
+}
+
+// sgrSubparameters applies an SGR sequence in which parameters have colon
+// separated subparameters. Each entry of params holds the values of one
+// parameter. Parameters without subparameters are applied with sgr
+func (vt *VT) sgrSubparameters(params [][]int) {
+	plain := []int{}
+	for _, param := range params {
+		if len(param) == 1 {
+			plain = append(plain, param[0])
+			continue
+		}
+		if len(plain) > 0 {
+			vt.sgr(plain)
+			plain = []int{}
+		}
+		switch param[0] {
+		case 4:
+			// Underline style CSI 4:Ps m
+			style := UnderlineStyle(param[1])
+			if style < UnderlineNone || style > UnderlineDashed {
+				continue
+			}
+			vt.setUnderline(style)
+		case 38, 48, 58:
+			// Colors CSI 38:5:n m, CSI 38:2:r:g:b m, or with a
+			// color space id CSI 38:2:id:r:g:b m
+			if len(param) > 5 && param[1] == 2 {
+				param = append([]int{param[0], param[1]}, param[3:]...)
+			}
+			color, _, ok := sgrColor(param)
+			if !ok {
+				continue
+			}
+			switch param[0] {
+			case 38:
+				vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
+			case 48:
+				vt.cursor.attrs = vt.cursor.attrs.Background(color)
+			case 58:
+				vt.cursor.ext.underlineColor = color
+			}
+		default:
+			// Subparameters of other attributes are ignored
+			vt.sgr(param[:1])
+		}
+	}
+	if len(plain) > 0 {
+		vt.sgr(plain)
+	}
+}
+
+// setUnderline sets the underline style of the cursor. tcell draws every
+// style as a plain underline
+func (vt *VT) setUnderline(style UnderlineStyle) {
+	vt.cursor.ext.underline = style
+	vt.cursor.attrs = vt.cursor.attrs.Underline(style != UnderlineNone)
+}
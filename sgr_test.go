@@ -97,3 +97,295 @@ func TestSGR(t *testing.T) {
 		})
 	}
 }
+
+func TestSGRUnderline(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		underline UnderlineStyle
+		color     tcell.Color
+	}{
+		{
+			name:      "single",
+			input:     "\x1b[4m",
+			underline: UnderlineSingle,
+		},
+		{
+			name:      "double",
+			input:     "\x1b[21m",
+			underline: UnderlineDouble,
+		},
+		{
+			name:      "curly",
+			input:     "\x1b[4:3m",
+			underline: UnderlineCurly,
+		},
+		{
+			name:      "dotted",
+			input:     "\x1b[4:4m",
+			underline: UnderlineDotted,
+		},
+		{
+			name:      "dashed",
+			input:     "\x1b[4:5m",
+			underline: UnderlineDashed,
+		},
+		{
+			name:  "style none",
+			input: "\x1b[4:3m\x1b[4:0m",
+		},
+		{
+			name:      "unknown style is ignored",
+			input:     "\x1b[4:3m\x1b[4:9m",
+			underline: UnderlineCurly,
+		},
+		{
+			name:  "not underlined",
+			input: "\x1b[4:3m\x1b[24m",
+		},
+		{
+			name:      "mixed with other attributes",
+			input:     "\x1b[1;4:2;31m",
+			underline: UnderlineDouble,
+		},
+		{
+			name:  "color palette",
+			input: "\x1b[58;5;196m",
+			color: tcell.PaletteColor(196),
+		},
+		{
+			name:  "color RGB",
+			input: "\x1b[58;2;1;2;3m",
+			color: tcell.NewRGBColor(1, 2, 3),
+		},
+		{
+			name:  "color palette subparameters",
+			input: "\x1b[58:5:196m",
+			color: tcell.PaletteColor(196),
+		},
+		{
+			name:  "color RGB subparameters",
+			input: "\x1b[58:2:1:2:3m",
+			color: tcell.NewRGBColor(1, 2, 3),
+		},
+		{
+			name:  "color RGB with color space",
+			input: "\x1b[58:2::1:2:3m",
+			color: tcell.NewRGBColor(1, 2, 3),
+		},
+		{
+			name:      "curly and colored",
+			input:     "\x1b[4:3;58:2::1:2:3m",
+			underline: UnderlineCurly,
+			color:     tcell.NewRGBColor(1, 2, 3),
+		},
+		{
+			name:      "default color",
+			input:     "\x1b[4:3;58;5;1m\x1b[59m",
+			underline: UnderlineCurly,
+		},
+		{
+			name:  "reset",
+			input: "\x1b[4:3;58;5;1m\x1b[0m",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.underline, vt.cursor.ext.underline)
+			assert.Equal(t, test.color, vt.cursor.ext.underlineColor)
+			_, _, attrs := vt.cursor.attrs.Decompose()
+			assert.Equal(t, test.underline != UnderlineNone, attrs&tcell.AttrUnderline != 0)
+		})
+	}
+}
+
+func TestSGRSubparameterColors(t *testing.T) {
+	vt := New()
+	_, _ = vt.Write([]byte("\x1b[38:5:1;48:2::1:2:3;1m"))
+	expected := tcell.StyleDefault.
+		Foreground(tcell.PaletteColor(1)).
+		Background(tcell.NewRGBColor(1, 2, 3)).
+		Bold(true)
+	assert.Equal(t, expected, vt.cursor.attrs)
+}
+
+func TestCellAt(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 2)
+	_, _ = vt.Write([]byte("\x1b[4:3;58;5;1mab\x1b[m\x1b[2Hつe\u0301"))
+
+	assert.Equal(t, Cell{
+		Content:        'a',
+		Width:          1,
+		Style:          tcell.StyleDefault.Underline(true),
+		Underline:      UnderlineCurly,
+		UnderlineColor: tcell.PaletteColor(1),
+	}, vt.CellAt(0, 0))
+	assert.Equal(t, Cell{}, vt.CellAt(2, 0))
+	assert.Equal(t, Cell{Content: 'つ', Width: 2}, vt.CellAt(0, 1))
+	assert.Equal(t, Cell{Content: ' '}, vt.CellAt(1, 1))
+	assert.Equal(t, Cell{Content: 'e', Combining: []rune{'\u0301'}, Width: 1}, vt.CellAt(2, 1))
+	assert.Equal(t, Cell{}, vt.CellAt(4, 0))
+	assert.Equal(t, Cell{}, vt.CellAt(0, -1))
+}
+
+func TestSGRExtAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		attrs ExtAttrMask
+		font  int
+		blink bool
+	}{
+		{
+			name:  "blink",
+			input: "\x1b[5m",
+			blink: true,
+		},
+		{
+			name:  "rapid blink",
+			input: "\x1b[6m",
+			attrs: ExtAttrRapidBlink,
+			blink: true,
+		},
+		{
+			name:  "slow blink after rapid blink",
+			input: "\x1b[6;5m",
+			blink: true,
+		},
+		{
+			name:  "not blinking",
+			input: "\x1b[6m\x1b[25m",
+		},
+		{
+			name:  "conceal",
+			input: "\x1b[8m",
+			attrs: ExtAttrConceal,
+		},
+		{
+			name:  "reveal",
+			input: "\x1b[8m\x1b[28m",
+		},
+		{
+			name:  "alternate font",
+			input: "\x1b[13m",
+			font:  3,
+		},
+		{
+			name:  "last alternate font",
+			input: "\x1b[19m",
+			font:  9,
+		},
+		{
+			name:  "primary font",
+			input: "\x1b[13m\x1b[10m",
+		},
+		{
+			name:  "framed",
+			input: "\x1b[51m",
+			attrs: ExtAttrFramed,
+		},
+		{
+			name:  "encircled",
+			input: "\x1b[51;52m",
+			attrs: ExtAttrEncircled,
+		},
+		{
+			name:  "not framed or encircled",
+			input: "\x1b[52m\x1b[54m",
+		},
+		{
+			name:  "overline",
+			input: "\x1b[53m",
+			attrs: ExtAttrOverline,
+		},
+		{
+			name:  "not overlined",
+			input: "\x1b[53m\x1b[55m",
+		},
+		{
+			name:  "superscript",
+			input: "\x1b[73m",
+			attrs: ExtAttrSuperscript,
+		},
+		{
+			name:  "subscript",
+			input: "\x1b[73;74m",
+			attrs: ExtAttrSubscript,
+		},
+		{
+			name:  "neither superscript nor subscript",
+			input: "\x1b[74m\x1b[75m",
+		},
+		{
+			name:  "combined",
+			input: "\x1b[6;8;12;51;53;73m",
+			attrs: ExtAttrRapidBlink | ExtAttrConceal | ExtAttrFramed | ExtAttrOverline | ExtAttrSuperscript,
+			font:  2,
+			blink: true,
+		},
+		{
+			name:  "with subparameters",
+			input: "\x1b[4:3;53m",
+			attrs: ExtAttrOverline,
+		},
+		{
+			name:  "reset",
+			input: "\x1b[6;8;12;51;53;73m\x1b[0m",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(2, 1)
+			_, _ = vt.Write([]byte(test.input + "a"))
+			cell := vt.CellAt(0, 0)
+			assert.Equal(t, test.attrs, cell.ExtAttrs)
+			assert.Equal(t, test.font, cell.Font)
+			_, _, attrs := cell.Style.Decompose()
+			assert.Equal(t, test.blink, attrs&tcell.AttrBlink != 0)
+		})
+	}
+}
+
+func TestConceal(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 1)
+	srf := &frame{w: 4, h: 1}
+	vt.SetSurface(srf)
+	_, _ = vt.Write([]byte("a\x1b[8mbe\u0301\x1b[28mc"))
+	vt.Draw()
+	chars := []rune{}
+	for _, cell := range srf.cells {
+		chars = append(chars, cell.ch)
+		if cell.ch == ' ' {
+			assert.Empty(t, cell.comb)
+		}
+	}
+	assert.Equal(t, []rune{'a', ' ', ' ', 'c'}, chars)
+
+	// Concealed text is kept
+	assert.Equal(t, "abe\u0301c", vt.String())
+	vt.SelectionStart(0, 0, SelectCharacter)
+	vt.SelectionExtend(3, 0)
+	assert.Equal(t, "abe\u0301c", vt.SelectedText())
+}
+
+func TestConcealWide(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 1)
+	srf := &frame{w: 2, h: 1}
+	vt.SetSurface(srf)
+	_, _ = vt.Write([]byte("ab"))
+	vt.Draw()
+	_, _ = vt.Write([]byte("\r\x1b[8mつ"))
+	srf.cells = nil
+	vt.Draw()
+	screen := []rune{'a', 'b'}
+	for _, cell := range srf.cells {
+		screen[cell.x] = cell.ch
+	}
+	assert.Equal(t, "  ", string(screen))
+}
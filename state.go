@@ -0,0 +1,719 @@
+package tcellterm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// stateVersion is the version of the format written by MarshalState
+const stateVersion = 1
+
+// The state format is a JSON object. Version 1 has the following fields:
+//
+//	version       always 1
+//	width         number of columns of the screens
+//	height        number of rows of the screens
+//	primary       rows of cells of the primary screen
+//	alt           rows of cells of the alternate screen
+//	history       rows of cells of scrollback history, oldest first
+//	cursor        the cursor, see below
+//	primaryState  the saved cursor of the primary screen, see below
+//	altState      the saved cursor of the alternate screen, see below
+//	margin        the scrolling margins as {top, bottom, left, right}
+//	modes         numbers of the ANSI modes which are set
+//	decModes      numbers of the DEC private modes which are set
+//	charsets      the charset state, see below
+//	tabStops      columns of the tab stops
+//	lastCol       true if the cursor is waiting to wrap
+//	primaryKeys   the kitty keyboard flag stack of the primary screen
+//	altKeys       the kitty keyboard flag stack of the alternate screen
+//	otherKeys     the level of xterm's modifyOtherKeys
+//	vt52Graphics  true if the VT52 graphics character set is selected
+//
+// Modes are listed as DECRQM reports them: DEC private mode 2 is listed while
+// the terminal is not in VT52 mode. Modes which share a state, such as 47, 1047
+// and 1049, are listed once by their lowest number. A synchronized update (mode
+// 2026) is not saved, as the frame it holds is not part of the state.
+//
+// Cells are objects with the keys "c" (rune), "m" (combining runes), "w"
+// (width), "s" (style), "x" (extended attributes) and "r" (soft-wrapped). Empty
+// keys are omitted. Styles are objects with the keys "fg", "bg", "attrs", "url"
+// and "urlId", holding the colors, attributes and hyperlink. Colors are palette
+// indexes, or strings "#rrggbb" for RGB colors, and are omitted for the default
+// color. Attributes are lists of the names "bold", "blink", "reverse",
+// "underline", "dim", "italic" and "strikethrough". Extended attributes are
+// objects with the keys "underline" (one of "single", "double", "curly",
+// "dotted" and "dashed"), "underlineColor" (color), "attrs" (list of the names
+// "rapidBlink", "conceal", "overline", "framed", "encircled", "superscript" and
+// "subscript") and "font" (alternate font, 1 through 9).
+//
+// The cursor is an object with the keys "row", "col", "style" (the DECSCUSR
+// cursor style), "attrs" (style) and "ext" (extended attributes). Saved cursors
+// are objects with the keys "cursor", "decawm", "decom" and "charsets".
+//
+// Charsets are objects with the keys "selected", "saved", "singleShift" and
+// "designations". Selected and saved are 0 through 3 for G0 through G3, and
+// designations holds the charset of G0 through G3 as the final character of
+// its SCS sequence: "B" for ASCII and "0" for DEC special graphics.
+type vtState struct {
+	Version      int             `json:"version"`
+	Width        int             `json:"width"`
+	Height       int             `json:"height"`
+	Primary      [][]cellState   `json:"primary"`
+	Alt          [][]cellState   `json:"alt"`
+	History      [][]cellState   `json:"history,omitempty"`
+	Cursor       cursorJSON      `json:"cursor"`
+	PrimaryState cursorStateJSON `json:"primaryState"`
+	AltState     cursorStateJSON `json:"altState"`
+	Margin       [4]int          `json:"margin"`
+	Modes        []int           `json:"modes,omitempty"`
+	DECModes     []int           `json:"decModes,omitempty"`
+	Charsets     charsetsJSON    `json:"charsets"`
+	TabStops     []int           `json:"tabStops"`
+	LastCol      bool            `json:"lastCol,omitempty"`
+	PrimaryKeys  []int           `json:"primaryKeys,omitempty"`
+	AltKeys      []int           `json:"altKeys,omitempty"`
+	OtherKeys    int             `json:"otherKeys,omitempty"`
+	VT52Graphics bool            `json:"vt52Graphics,omitempty"`
+}
+
+type cellState struct {
+	Content   rune       `json:"c,omitempty"`
+	Combining []rune     `json:"m,omitempty"`
+	Width     int        `json:"w,omitempty"`
+	Style     *styleJSON `json:"s,omitempty"`
+	Ext       *extJSON   `json:"x,omitempty"`
+	Wrapped   bool       `json:"r,omitempty"`
+}
+
+type styleJSON struct {
+	Fg    colorJSON `json:"fg,omitempty"`
+	Bg    colorJSON `json:"bg,omitempty"`
+	Attrs attrsJSON `json:"attrs,omitempty"`
+	URL   string    `json:"url,omitempty"`
+	URLID string    `json:"urlId,omitempty"`
+}
+
+type extJSON struct {
+	Underline      underlineJSON `json:"underline,omitempty"`
+	UnderlineColor colorJSON     `json:"underlineColor,omitempty"`
+	Attrs          extAttrsJSON  `json:"attrs,omitempty"`
+	Font           int           `json:"font,omitempty"`
+}
+
+type cursorJSON struct {
+	Row   int               `json:"row"`
+	Col   int               `json:"col"`
+	Style tcell.CursorStyle `json:"style,omitempty"`
+	Attrs *styleJSON        `json:"attrs,omitempty"`
+	Ext   *extJSON          `json:"ext,omitempty"`
+}
+
+type cursorStateJSON struct {
+	Cursor   cursorJSON   `json:"cursor"`
+	DECAWM   bool         `json:"decawm,omitempty"`
+	DECOM    bool         `json:"decom,omitempty"`
+	Charsets charsetsJSON `json:"charsets"`
+}
+
+type charsetsJSON struct {
+	Selected     charsetDesignator `json:"selected"`
+	Saved        charsetDesignator `json:"saved"`
+	SingleShift  bool              `json:"singleShift,omitempty"`
+	Designations [4]charsetJSON    `json:"designations"`
+}
+
+// MarshalState serializes the complete state of the screens, cursor, and
+// modes of the terminal. The state can be loaded into another VT with
+// RestoreState
+func (vt *VT) MarshalState() ([]byte, error) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	state := vtState{
+		Version:      stateVersion,
+		Width:        vt.width(),
+		Height:       vt.height(),
+		Primary:      gridToState(vt.primaryScreen),
+		Alt:          gridToState(vt.altScreen),
+		History:      gridToState(vt.history),
+		Cursor:       cursorToState(vt.cursor),
+		PrimaryState: cursorStateToState(vt.primaryState),
+		AltState:     cursorStateToState(vt.altState),
+		Margin: [4]int{
+			int(vt.margin.top),
+			int(vt.margin.bottom),
+			int(vt.margin.left),
+			int(vt.margin.right),
+		},
+		Modes:        modesToState(vt.mode&^syncUpdate, ansiModes),
+		DECModes:     modesToState(vt.mode&^syncUpdate, decModes),
+		Charsets:     charsetsToState(vt.charsets),
+		TabStops:     make([]int, 0, len(vt.tabStop)),
+		LastCol:      vt.lastCol,
+		PrimaryKeys:  vt.primaryKeyFlags,
+		AltKeys:      vt.altKeyFlags,
+		OtherKeys:    vt.modifyOtherKeys,
+		VT52Graphics: vt.vt52Graphics,
+	}
+	for _, ts := range vt.tabStop {
+		state.TabStops = append(state.TabStops, int(ts))
+	}
+	return json.Marshal(state)
+}
+
+// RestoreState replaces the state of the terminal with one serialized by
+// MarshalState. The terminal takes the size of the restored state
+func (vt *VT) RestoreState(data []byte) error {
+	state := vtState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("restore state: %w", err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("restore state: unsupported version %d", state.Version)
+	}
+	if err := state.validate(); err != nil {
+		return fmt.Errorf("restore state: %w", err)
+	}
+	ansi, err := stateToModes(state.Modes, ansiModes)
+	if err != nil {
+		return fmt.Errorf("restore state: %w", err)
+	}
+	dec, err := stateToModes(state.DECModes, decModes)
+	if err != nil {
+		return fmt.Errorf("restore state: %w", err)
+	}
+	primary, err := stateToGrid(state.Primary, state.Width, state.Height)
+	if err != nil {
+		return fmt.Errorf("restore state: primary screen: %w", err)
+	}
+	alt, err := stateToGrid(state.Alt, state.Width, state.Height)
+	if err != nil {
+		return fmt.Errorf("restore state: alternate screen: %w", err)
+	}
+	history, err := stateToGrid(state.History, -1, len(state.History))
+	if err != nil {
+		return fmt.Errorf("restore state: history: %w", err)
+	}
+
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.primaryScreen = primary
+	vt.altScreen = alt
+	vt.history = history
+	vt.viewOffset = 0
+	vt.trimHistory()
+	vt.clearSelection()
+	vt.setHighlights(nil)
+	vt.cursor = stateToCursor(state.Cursor)
+	vt.primaryState = stateToCursorState(state.PrimaryState)
+	vt.altState = stateToCursorState(state.AltState)
+	vt.margin = margin{
+		top:    row(state.Margin[0]),
+		bottom: row(state.Margin[1]),
+		left:   column(state.Margin[2]),
+		right:  column(state.Margin[3]),
+	}
+	// A synchronized update in progress ends, and one in the state is
+	// ignored
+	vt.mode = (ansi | dec) &^ syncUpdate
+	vt.frozen = nil
+	vt.syncChanged = false
+	if vt.syncTimer != nil {
+		vt.syncTimer.Stop()
+		vt.syncTimer = nil
+	}
+	vt.charsets = stateToCharsets(state.Charsets)
+	vt.tabStop = make([]column, 0, len(state.TabStops))
+	for _, ts := range state.TabStops {
+		vt.tabStop = append(vt.tabStop, column(ts))
+	}
+	vt.lastCol = state.LastCol
+	vt.primaryKeyFlags = state.PrimaryKeys
+	vt.altKeyFlags = state.AltKeys
+	vt.modifyOtherKeys = state.OtherKeys
+	vt.vt52Graphics = state.VT52Graphics
+	vt.vt52Args = nil
+	switch vt.mode & smcup {
+	case 0:
+		vt.activeScreen = vt.primaryScreen
+	default:
+		vt.activeScreen = vt.altScreen
+	}
+	vt.damageAll()
+	return nil
+}
+
+// validate checks that the margins and cursors of the state are inside the
+// screen, and that every cell fits in its row
+func (s *vtState) validate() error {
+	if s.Width < 1 || s.Height < 1 {
+		return fmt.Errorf("invalid size %dx%d", s.Width, s.Height)
+	}
+	top, bottom, left, right := s.Margin[0], s.Margin[1], s.Margin[2], s.Margin[3]
+	if top < 0 || top > bottom || bottom >= s.Height {
+		return fmt.Errorf("invalid top and bottom margins %d, %d", top, bottom)
+	}
+	if left < 0 || left > right || right >= s.Width {
+		return fmt.Errorf("invalid left and right margins %d, %d", left, right)
+	}
+	cursors := []struct {
+		name   string
+		cursor cursorJSON
+	}{
+		{"cursor", s.Cursor},
+		{"primary saved cursor", s.PrimaryState.Cursor},
+		{"alternate saved cursor", s.AltState.Cursor},
+	}
+	for _, c := range cursors {
+		if c.cursor.Row < 0 || c.cursor.Row >= s.Height || c.cursor.Col < 0 || c.cursor.Col >= s.Width {
+			return fmt.Errorf("%s %d,%d is outside the screen", c.name, c.cursor.Row, c.cursor.Col)
+		}
+	}
+	grids := []struct {
+		name string
+		rows [][]cellState
+	}{
+		{"primary screen", s.Primary},
+		{"alternate screen", s.Alt},
+		{"history", s.History},
+	}
+	for _, g := range grids {
+		for r, cells := range g.rows {
+			for col, c := range cells {
+				if c.Width < 0 || c.Width > 2 {
+					return fmt.Errorf("%s: cell %d,%d has invalid width %d", g.name, r, col, c.Width)
+				}
+				if col+c.Width > len(cells) {
+					return fmt.Errorf("%s: wide cell %d,%d runs past the end of the row", g.name, r, col)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func gridToState(grid [][]cell) [][]cellState {
+	rows := make([][]cellState, 0, len(grid))
+	for _, line := range grid {
+		cells := make([]cellState, 0, len(line))
+		for _, c := range line {
+			cells = append(cells, cellState{
+				Content:   c.content,
+				Combining: c.combining,
+				Width:     c.width,
+				Style:     styleToState(c.attrs, c.ext.link),
+				Ext:       extToState(c.ext),
+				Wrapped:   c.wrapped,
+			})
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// stateToGrid converts rows of serialized cells to a grid of height h and
+// width w. If w is negative, rows may be of any width
+func stateToGrid(rows [][]cellState, w int, h int) ([][]cell, error) {
+	if len(rows) != h {
+		return nil, fmt.Errorf("expected %d rows, got %d", h, len(rows))
+	}
+	grid := make([][]cell, 0, len(rows))
+	for i, cells := range rows {
+		if w >= 0 && len(cells) != w {
+			return nil, fmt.Errorf("row %d: expected %d columns, got %d", i, w, len(cells))
+		}
+		line := make([]cell, 0, len(cells))
+		for _, c := range cells {
+			line = append(line, cell{
+				content:   c.Content,
+				combining: c.Combining,
+				width:     c.Width,
+				attrs:     stateToStyle(c.Style),
+				ext:       stateToExt(c.Ext, c.Style),
+				wrapped:   c.Wrapped,
+			})
+		}
+		grid = append(grid, line)
+	}
+	return grid, nil
+}
+
+// styleToState serializes a style and the hyperlink of a cell
+func styleToState(s tcell.Style, link hyperlink) *styleJSON {
+	if s == tcell.StyleDefault && link == (hyperlink{}) {
+		return nil
+	}
+	fg, bg, attrs := s.Decompose()
+	return &styleJSON{
+		Fg:    colorJSON(fg),
+		Bg:    colorJSON(bg),
+		Attrs: attrsJSON(attrs),
+		URL:   link.url,
+		URLID: link.id,
+	}
+}
+
+func stateToStyle(s *styleJSON) tcell.Style {
+	style := tcell.StyleDefault
+	if s == nil {
+		return style
+	}
+	return style.
+		Foreground(tcell.Color(s.Fg)).
+		Background(tcell.Color(s.Bg)).
+		Attributes(tcell.AttrMask(s.Attrs))
+}
+
+// extToState serializes extended attributes. The hyperlink is serialized with
+// the style
+func extToState(e extAttrs) *extJSON {
+	e.link = hyperlink{}
+	if e == (extAttrs{}) {
+		return nil
+	}
+	return &extJSON{
+		Underline:      underlineJSON(e.underline),
+		UnderlineColor: colorJSON(e.underlineColor),
+		Attrs:          extAttrsJSON(e.attrs),
+		Font:           e.font,
+	}
+}
+
+func stateToExt(e *extJSON, s *styleJSON) extAttrs {
+	ext := extAttrs{}
+	if e != nil {
+		ext = extAttrs{
+			underline:      UnderlineStyle(e.Underline),
+			underlineColor: tcell.Color(e.UnderlineColor),
+			attrs:          ExtAttrMask(e.Attrs),
+			font:           e.Font,
+		}
+	}
+	if s != nil {
+		ext.link = hyperlink{
+			url: s.URL,
+			id:  s.URLID,
+		}
+	}
+	return ext
+}
+
+func cursorToState(c cursor) cursorJSON {
+	return cursorJSON{
+		Row:   int(c.row),
+		Col:   int(c.col),
+		Style: c.style,
+		Attrs: styleToState(c.attrs, c.ext.link),
+		Ext:   extToState(c.ext),
+	}
+}
+
+func stateToCursor(c cursorJSON) cursor {
+	return cursor{
+		row:   row(c.Row),
+		col:   column(c.Col),
+		style: c.Style,
+		attrs: stateToStyle(c.Attrs),
+		ext:   stateToExt(c.Ext, c.Attrs),
+	}
+}
+
+func cursorStateToState(s cursorState) cursorStateJSON {
+	return cursorStateJSON{
+		Cursor:   cursorToState(s.cursor),
+		DECAWM:   s.decawm,
+		DECOM:    s.decom,
+		Charsets: charsetsToState(s.charsets),
+	}
+}
+
+func stateToCursorState(s cursorStateJSON) cursorState {
+	return cursorState{
+		cursor:   stateToCursor(s.Cursor),
+		decawm:   s.DECAWM,
+		decom:    s.DECOM,
+		charsets: stateToCharsets(s.Charsets),
+	}
+}
+
+func charsetsToState(c charsets) charsetsJSON {
+	return charsetsJSON{
+		Selected:    c.selected,
+		Saved:       c.saved,
+		SingleShift: c.singleShift,
+		Designations: [4]charsetJSON{
+			charsetJSON(c.designations[g0]),
+			charsetJSON(c.designations[g1]),
+			charsetJSON(c.designations[g2]),
+			charsetJSON(c.designations[g3]),
+		},
+	}
+}
+
+func stateToCharsets(c charsetsJSON) charsets {
+	return charsets{
+		selected:    c.Selected,
+		saved:       c.Saved,
+		singleShift: c.SingleShift,
+		designations: map[charsetDesignator]charset{
+			g0: charset(c.Designations[0]),
+			g1: charset(c.Designations[1]),
+			g2: charset(c.Designations[2]),
+			g3: charset(c.Designations[3]),
+		},
+	}
+}
+
+// modesToState lists the numbers of the modes of the table which are set, as
+// DECRQM reports them. Modes which share a flag are listed by their lowest
+// number
+func modesToState(m mode, modes map[int]modeInfo) []int {
+	nums := make([]int, 0, len(modes))
+	for n := range modes {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	listed := mode(0)
+	set := []int{}
+	for _, n := range nums {
+		info := modes[n]
+		if info.flag == 0 || info.flag&listed != 0 {
+			continue
+		}
+		listed |= info.flag
+		if (m&info.flag != 0) != info.inverted {
+			set = append(set, n)
+		}
+	}
+	return set
+}
+
+// stateToModes returns the mode flags for the list of mode numbers of the
+// table which are set
+func stateToModes(set []int, modes map[int]modeInfo) (mode, error) {
+	listed := map[int]bool{}
+	for _, n := range set {
+		info, ok := modes[n]
+		if !ok || info.flag == 0 {
+			return 0, fmt.Errorf("unknown mode %d", n)
+		}
+		listed[n] = true
+	}
+	m := mode(0)
+	for n, info := range modes {
+		if info.flag != 0 && listed[n] != info.inverted {
+			m |= info.flag
+		}
+	}
+	return m, nil
+}
+
+// colorJSON is a color in the state format: a palette index, or "#rrggbb" for
+// an RGB color. The default color is null
+type colorJSON tcell.Color
+
+func (c colorJSON) MarshalJSON() ([]byte, error) {
+	color := tcell.Color(c)
+	switch {
+	case color == tcell.ColorDefault:
+		return []byte("null"), nil
+	case color.IsRGB():
+		return json.Marshal(fmt.Sprintf("#%06x", color.Hex()))
+	case color&tcell.ColorValid != 0 && color-tcell.ColorValid < 256:
+		return json.Marshal(int(color - tcell.ColorValid))
+	default:
+		return nil, fmt.Errorf("unsupported color %d", color)
+	}
+}
+
+func (c *colorJSON) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = colorJSON(tcell.ColorDefault)
+		return nil
+	}
+	index := 0
+	if err := json.Unmarshal(data, &index); err == nil {
+		if index < 0 || index > 255 {
+			return fmt.Errorf("invalid palette index %d", index)
+		}
+		*c = colorJSON(tcell.PaletteColor(index))
+		return nil
+	}
+	hex := ""
+	if err := json.Unmarshal(data, &hex); err != nil {
+		return fmt.Errorf("invalid color %s", data)
+	}
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 24)
+	if err != nil || len(hex) != 7 || hex[0] != '#' {
+		return fmt.Errorf("invalid color %q", hex)
+	}
+	*c = colorJSON(tcell.NewHexColor(int32(rgb)))
+	return nil
+}
+
+// attrNames are the names of the attributes of a tcell.Style in the state
+// format
+var attrNames = []string{
+	"bold",
+	"blink",
+	"reverse",
+	"underline",
+	"dim",
+	"italic",
+	"strikethrough",
+}
+
+// extAttrNames are the names of the ExtAttrMask attributes in the state format
+var extAttrNames = []string{
+	"rapidBlink",
+	"conceal",
+	"overline",
+	"framed",
+	"encircled",
+	"superscript",
+	"subscript",
+}
+
+// flagsToNames lists the names of the bits of mask. Bit n is named by names[n]
+func flagsToNames(mask int, names []string) ([]string, error) {
+	list := []string{}
+	for i, name := range names {
+		if mask&(1<<i) != 0 {
+			list = append(list, name)
+		}
+	}
+	if mask>>len(names) != 0 {
+		return nil, fmt.Errorf("unsupported attributes %#x", mask)
+	}
+	return list, nil
+}
+
+// namesToFlags returns the mask of the named bits. Bit n is named by names[n]
+func namesToFlags(list []string, names []string) (int, error) {
+	mask := 0
+outer:
+	for _, name := range list {
+		for i := range names {
+			if names[i] == name {
+				mask |= 1 << i
+				continue outer
+			}
+		}
+		return 0, fmt.Errorf("unknown attribute %q", name)
+	}
+	return mask, nil
+}
+
+// attrsJSON is a tcell.AttrMask in the state format
+type attrsJSON tcell.AttrMask
+
+func (a attrsJSON) MarshalJSON() ([]byte, error) {
+	list, err := flagsToNames(int(a), attrNames)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(list)
+}
+
+func (a *attrsJSON) UnmarshalJSON(data []byte) error {
+	list := []string{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	mask, err := namesToFlags(list, attrNames)
+	*a = attrsJSON(mask)
+	return err
+}
+
+// extAttrsJSON is an ExtAttrMask in the state format
+type extAttrsJSON ExtAttrMask
+
+func (a extAttrsJSON) MarshalJSON() ([]byte, error) {
+	list, err := flagsToNames(int(a), extAttrNames)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(list)
+}
+
+func (a *extAttrsJSON) UnmarshalJSON(data []byte) error {
+	list := []string{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	mask, err := namesToFlags(list, extAttrNames)
+	*a = extAttrsJSON(mask)
+	return err
+}
+
+// underlineNames are the names of the underline styles in the state format,
+// indexed by UnderlineStyle
+var underlineNames = []string{
+	UnderlineNone:   "",
+	UnderlineSingle: "single",
+	UnderlineDouble: "double",
+	UnderlineCurly:  "curly",
+	UnderlineDotted: "dotted",
+	UnderlineDashed: "dashed",
+}
+
+// underlineJSON is an UnderlineStyle in the state format
+type underlineJSON UnderlineStyle
+
+func (u underlineJSON) MarshalJSON() ([]byte, error) {
+	if u < 0 || int(u) >= len(underlineNames) {
+		return nil, fmt.Errorf("unsupported underline style %d", u)
+	}
+	return json.Marshal(underlineNames[u])
+}
+
+func (u *underlineJSON) UnmarshalJSON(data []byte) error {
+	name := ""
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for i := range underlineNames {
+		if underlineNames[i] == name {
+			*u = underlineJSON(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown underline style %q", name)
+}
+
+// charsetFinals are the final characters of the SCS sequences which designate
+// each charset
+var charsetFinals = map[charset]string{
+	ascii:                    "B",
+	decSpecialAndLineDrawing: "0",
+}
+
+// charsetJSON is a charset in the state format
+type charsetJSON charset
+
+func (c charsetJSON) MarshalJSON() ([]byte, error) {
+	final, ok := charsetFinals[charset(c)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %d", c)
+	}
+	return json.Marshal(final)
+}
+
+func (c *charsetJSON) UnmarshalJSON(data []byte) error {
+	final := ""
+	if err := json.Unmarshal(data, &final); err != nil {
+		return err
+	}
+	for cs, f := range charsetFinals {
+		if f == final {
+			*c = charsetJSON(cs)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown charset %q", final)
+}
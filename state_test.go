@@ -0,0 +1,217 @@
+package tcellterm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	for _, path := range captures(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			vt := New()
+			vt.Resize(captureSize(t, path))
+			_, _ = vt.Write(data)
+			state, err := vt.MarshalState()
+			require.NoError(t, err)
+
+			restored := New()
+			require.NoError(t, restored.RestoreState(state))
+			assert.Equal(t, vt.String(), restored.String())
+			row, col, _, vis := vt.Cursor()
+			rRow, rCol, _, rVis := restored.Cursor()
+			assert.Equal(t, []interface{}{row, col, vis}, []interface{}{rRow, rCol, rVis})
+
+			again, err := restored.MarshalState()
+			require.NoError(t, err)
+			assert.Equal(t, string(state), string(again))
+
+			// Both terminals behave the same for further input
+			more := "\x1b[1;1Hmore\x1b[5Babc\x1b[2K\r\nxyz\x1b8\x1b[?1049lend"
+			_, _ = vt.Write([]byte(more))
+			_, _ = restored.Write([]byte(more))
+			assert.Equal(t, vt.String(), restored.String())
+		})
+	}
+}
+
+func TestStateGolden(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	input := "\x1b[1;31ma\x1b]8;id=x;https://example.com\x1b\\b\x1b]8;;\x1b\\\x1b[mé\x1b(0q\x1b(B\x1b7つ\x1b[2;3r"
+	_, _ = vt.Write([]byte(input))
+	state, err := vt.MarshalState()
+	require.NoError(t, err)
+
+	golden := filepath.Join("tests", "golden", "state.json")
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+		require.NoError(t, os.WriteFile(golden, append(state, '\n'), 0o644))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(bytes.TrimSpace(expected)), string(state))
+
+	restored := New()
+	require.NoError(t, restored.RestoreState(expected))
+	assert.Equal(t, vt.String(), restored.String())
+	assert.Equal(t, vt.primaryScreen, restored.primaryScreen)
+}
+
+func TestRestoreStateErrors(t *testing.T) {
+	vt := New()
+	assert.Error(t, vt.RestoreState([]byte("not json")))
+	assert.Error(t, vt.RestoreState([]byte(`{"version":99}`)))
+	assert.Error(t, vt.RestoreState([]byte(`{"version":1,"width":2,"height":1,"primary":[[{}]]}`)))
+
+	// Margins and cursors must be inside the screen, cells must fit in
+	// their rows, and values must be known
+	golden, err := os.ReadFile(filepath.Join("tests", "golden", "state.json"))
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{
+			name: "bottom margin",
+			old:  `"margin":[1,2,0,3]`,
+			new:  `"margin":[1,40,0,3]`,
+		},
+		{
+			name: "right margin",
+			old:  `"margin":[1,2,0,3]`,
+			new:  `"margin":[1,2,0,30]`,
+		},
+		{
+			name: "top below bottom",
+			old:  `"margin":[1,2,0,3]`,
+			new:  `"margin":[2,1,0,3]`,
+		},
+		{
+			name: "left right of right",
+			old:  `"margin":[1,2,0,3]`,
+			new:  `"margin":[1,2,3,2]`,
+		},
+		{
+			name: "negative margin",
+			old:  `"margin":[1,2,0,3]`,
+			new:  `"margin":[1,2,-1,3]`,
+		},
+		{
+			name: "cursor",
+			old:  `"cursor":{"row":0,"col":0`,
+			new:  `"cursor":{"row":50,"col":50`,
+		},
+		{
+			name: "saved cursor",
+			old:  `"cursor":{"row":0,"col":3`,
+			new:  `"cursor":{"row":3,"col":3`,
+		},
+		{
+			name: "unknown mode",
+			old:  `"decModes":[2,7,25]`,
+			new:  `"decModes":[2,7,25,12345]`,
+		},
+		{
+			name: "unknown attribute",
+			old:  `"attrs":["bold"]`,
+			new:  `"attrs":["shiny"]`,
+		},
+		{
+			name: "palette index",
+			old:  `"fg":1,`,
+			new:  `"fg":256,`,
+		},
+		{
+			name: "rgb color",
+			old:  `"fg":1,`,
+			new:  `"fg":"#12345",`,
+		},
+		{
+			name: "charset",
+			old:  `"designations":["B"`,
+			new:  `"designations":["Z"`,
+		},
+		{
+			name: "negative width",
+			old:  `{"c":12388,"w":2}`,
+			new:  `{"c":12388,"w":-1}`,
+		},
+		{
+			name: "too wide",
+			old:  `{"c":12388,"w":2}`,
+			new:  `{"c":12388,"w":3}`,
+		},
+		{
+			name: "wide cell past the end of the row",
+			old:  `{"c":9472,"w":1,"r":true}`,
+			new:  `{"c":9472,"w":2,"r":true}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Contains(t, string(golden), test.old)
+			data := strings.Replace(string(golden), test.old, test.new, 1)
+			vt := New()
+			vt.Resize(4, 3)
+			_, _ = vt.Write([]byte("abc"))
+			assert.Error(t, vt.RestoreState([]byte(data)))
+			// The terminal is unchanged
+			assert.Equal(t, "abc \n    \n    ", vt.String())
+		})
+	}
+}
+
+func TestRestoreStateScrollback(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 2)
+	_, _ = vt.Write([]byte("a\r\nb\r\nc\r\nd"))
+	state, err := vt.MarshalState()
+	require.NoError(t, err)
+
+	restored := New()
+	restored.Scrollback = 1
+	require.NoError(t, restored.RestoreState(state))
+	assert.Len(t, restored.history, 1)
+	assert.Equal(t, 'b', restored.history[0][0].rune())
+}
+
+func TestStateStyles(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 1)
+	_, _ = vt.Write([]byte("\x1b[38;2;1;2;3;48;5;200;3;7;9m\x1b[4:3;58;2;255;0;0;8;53ma"))
+	state, err := vt.MarshalState()
+	require.NoError(t, err)
+	assert.Contains(t, string(state), `"s":{"fg":"#010203","bg":200,"attrs":["reverse","underline","italic","strikethrough"]}`)
+	assert.Contains(t, string(state), `"x":{"underline":"curly","underlineColor":"#ff0000","attrs":["conceal","overline"]}`)
+
+	restored := New()
+	require.NoError(t, restored.RestoreState(state))
+	assert.Equal(t, vt.primaryScreen, restored.primaryScreen)
+}
+
+func TestStateModes(t *testing.T) {
+	// Every mode but a synchronized update survives a round trip
+	for bit := kam; bit <= vt52; bit <<= 1 {
+		if bit == syncUpdate {
+			continue
+		}
+		vt := New()
+		vt.Resize(2, 2)
+		vt.mode = bit
+		state, err := vt.MarshalState()
+		require.NoError(t, err)
+		restored := New()
+		require.NoError(t, restored.RestoreState(state))
+		assert.Equal(t, bit, restored.mode, "mode %#x", uint64(bit))
+	}
+}
@@ -0,0 +1,132 @@
+package tcellterm
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// syncTimeout is the longest a synchronized update may last before the
+// changes are drawn anyway
+const syncTimeout = time.Second
+
+// frame is a copy of the view as it was drawn. It records the contents set on
+// it as a Surface
+type frame struct {
+	w     int
+	h     int
+	cells []frameCell
+	// damaged is set when the frame must be painted again
+	damaged bool
+}
+
+type frameCell struct {
+	x     int
+	y     int
+	ch    rune
+	comb  []rune
+	style tcell.Style
+}
+
+func (f *frame) SetContent(x int, y int, ch rune, comb []rune, style tcell.Style) {
+	f.cells = append(f.cells, frameCell{
+		x:     x,
+		y:     y,
+		ch:    ch,
+		comb:  comb,
+		style: style,
+	})
+}
+
+func (f *frame) Size() (int, int) {
+	return f.w, f.h
+}
+
+// beginSync starts a synchronized update (DECSET 2026). Until it ends, no
+// EventRedraw is posted and Draw paints the view as it is now
+func (vt *VT) beginSync() {
+	if vt.mode&syncUpdate != 0 {
+		return
+	}
+	vt.mode |= syncUpdate
+	f := &frame{
+		w: vt.width(),
+		h: vt.height(),
+	}
+	srf := vt.surface
+	vt.surface = f
+	selStart, selEnd := vt.selectionBounds()
+	for row := 0; row < vt.height(); row += 1 {
+		vt.drawRow(row, selStart, selEnd)
+	}
+	vt.surface = srf
+	vt.frozen = f
+
+	// Draw the update anyway if the application never ends it
+	vt.syncGen += 1
+	gen := vt.syncGen
+	vt.syncTimer = time.AfterFunc(syncTimeout, func() {
+		vt.expireSync(gen)
+	})
+}
+
+// expireSync ends the synchronized update gen when it has lasted too long. If
+// the terminal is reading from an application, the changes are drawn by the
+// goroutine reading from it
+func (vt *VT) expireSync(gen int) {
+	vt.mu.Lock()
+	if vt.syncGen != gen || vt.mode&syncUpdate == 0 {
+		vt.mu.Unlock()
+		return
+	}
+	vt.mode &^= syncUpdate
+	vt.frozen = nil
+	vt.syncTimer = nil
+	if vt.syncChanged {
+		vt.syncChanged = false
+		vt.dirty = true
+	}
+	running := vt.parser != nil
+	vt.mu.Unlock()
+	if running {
+		vt.requestRedraw()
+		return
+	}
+	vt.flushRedraw(false)
+}
+
+// endSync ends a synchronized update. Changes made during the update are
+// drawn at once
+func (vt *VT) endSync() {
+	if vt.mode&syncUpdate == 0 {
+		return
+	}
+	vt.mode &^= syncUpdate
+	vt.frozen = nil
+	if vt.syncTimer != nil {
+		vt.syncTimer.Stop()
+		vt.syncTimer = nil
+	}
+	if vt.syncChanged {
+		vt.syncChanged = false
+		vt.changed()
+	}
+}
+
+// drawFrozen paints the frame saved at the start of a synchronized update,
+// if the Surface needs it
+func (vt *VT) drawFrozen() []Rect {
+	if !vt.frozen.damaged {
+		return nil
+	}
+	vt.frozen.damaged = false
+	for _, c := range vt.frozen.cells {
+		vt.surface.SetContent(c.x, c.y, c.ch, c.comb, c.style)
+	}
+	return []Rect{{
+		X:      0,
+		Y:      0,
+		Width:  vt.frozen.w,
+		Height: vt.frozen.h,
+	}}
+}
@@ -0,0 +1,93 @@
+package tcellterm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynchronizedUpdate(t *testing.T) {
+	setup := func() (*VT, *testSurface, *int) {
+		vt := New()
+		vt.FrameRate = 0
+		srf := newTestSurface(4, 2)
+		vt.SetSurface(srf)
+		vt.Resize(4, 2)
+		redraws := 0
+		vt.Attach(func(ev tcell.Event) {
+			if _, ok := ev.(*EventRedraw); ok {
+				redraws += 1
+			}
+		})
+		_, _ = vt.Write([]byte("ab"))
+		vt.Draw()
+		redraws = 0
+		return vt, srf, &redraws
+	}
+
+	t.Run("frame is held", func(t *testing.T) {
+		vt, srf, redraws := setup()
+		_, _ = vt.Write([]byte("\x1b[?2026h\x1b[Hxy"))
+		assert.Equal(t, 0, *redraws)
+		assert.Nil(t, vt.Draw())
+		assert.Equal(t, 'a', srf.cells[0][0])
+
+		// A host which repaints everything gets the old frame
+		vt.Invalidate()
+		vt.Draw()
+		assert.Equal(t, 'a', srf.cells[0][0])
+
+		_, _ = vt.Write([]byte("\x1b[?2026l"))
+		assert.Equal(t, 1, *redraws)
+		vt.Draw()
+		assert.Equal(t, 'x', srf.cells[0][0])
+		assert.Equal(t, 'y', srf.cells[0][1])
+	})
+
+	t.Run("resize ends update", func(t *testing.T) {
+		vt, srf, _ := setup()
+		_, _ = vt.Write([]byte("\x1b[?2026h\x1b[Hx"))
+		vt.Resize(4, 2)
+		vt.Draw()
+		assert.Equal(t, 'x', srf.cells[0][0])
+		assert.Zero(t, vt.mode&syncUpdate)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		vt, srf, redraws := setup()
+		_, _ = vt.Write([]byte("\x1b[?2026h\x1b[Hx"))
+		vt.syncTimer.Stop()
+		vt.expireSync(vt.syncGen)
+		assert.Equal(t, 1, *redraws)
+		assert.Zero(t, vt.mode&syncUpdate)
+		vt.Draw()
+		assert.Equal(t, 'x', srf.cells[0][0])
+	})
+
+	t.Run("restored state", func(t *testing.T) {
+		vt, _, _ := setup()
+		_, _ = vt.Write([]byte("\x1b[?2026h\x1b[Hx"))
+		state, err := vt.MarshalState()
+		assert.NoError(t, err)
+
+		restored, srf, redraws := setup()
+		_, _ = restored.Write([]byte("\x1b[?2026h"))
+		assert.NoError(t, restored.RestoreState(state))
+		assert.Zero(t, restored.mode&syncUpdate)
+		_, _ = restored.Write([]byte("y"))
+		assert.Equal(t, 1, *redraws)
+		restored.Draw()
+		assert.Equal(t, 'x', srf.cells[0][0])
+		assert.Equal(t, 'y', srf.cells[0][1])
+	})
+
+	t.Run("DECRQM", func(t *testing.T) {
+		vt, _, _ := setup()
+		buf := &bytes.Buffer{}
+		vt.ReplyWriter = buf
+		_, _ = vt.Write([]byte("\x1b[?2026$p\x1b[?2026h\x1b[?2026$p\x1b[?9999$p"))
+		assert.Equal(t, "\x1b[?2026;2$y\x1b[?2026;1$y\x1b[?9999;0$y", buf.String())
+	})
+}
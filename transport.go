@@ -0,0 +1,77 @@
+package tcellterm
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// Transport is the byte stream between a VT and an application. Reads return
+// output of the application, and writes carry keys, mouse events and replies
+// to the application. A Transport may be a local pty, an SSH channel, a serial
+// device or any other connection
+type Transport interface {
+	io.ReadWriteCloser
+	// Resize informs the application of a new terminal size in cells, and
+	// in pixels if known. Pixel sizes are 0 when unknown
+	Resize(cols int, rows int, xpix int, ypix int) error
+}
+
+// ptyTransport is a Transport to a command running in a local pty
+type ptyTransport struct {
+	pty *os.File
+	cmd *exec.Cmd
+}
+
+// startPty starts cmd in a new session with a pty of w columns and h rows as
+// its controlling terminal
+func startPty(cmd *exec.Cmd, w int, h int) (*ptyTransport, error) {
+	winsize := pty.Winsize{
+		Cols: uint16(w),
+		Rows: uint16(h),
+	}
+	f, err := pty.StartWithAttrs(
+		cmd,
+		&winsize,
+		&syscall.SysProcAttr{
+			Setsid:  true,
+			Setctty: true,
+			Ctty:    1,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &ptyTransport{
+		pty: f,
+		cmd: cmd,
+	}, nil
+}
+
+func (t *ptyTransport) Read(p []byte) (int, error) {
+	return t.pty.Read(p)
+}
+
+func (t *ptyTransport) Write(p []byte) (int, error) {
+	return t.pty.Write(p)
+}
+
+// Close kills the command and closes the pty
+func (t *ptyTransport) Close() error {
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+		t.cmd.Wait()
+	}
+	return t.pty.Close()
+}
+
+func (t *ptyTransport) Resize(cols int, rows int, xpix int, ypix int) error {
+	return pty.Setsize(t.pty, &pty.Winsize{
+		Cols: uint16(cols),
+		Rows: uint16(rows),
+		X:    uint16(xpix),
+		Y:    uint16(ypix),
+	})
+}
@@ -0,0 +1,66 @@
+package tcellterm
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTransport struct {
+	io.Reader
+	mu      sync.Mutex
+	written bytes.Buffer
+	sizes   [][2]int
+	closed  bool
+}
+
+func (t *testTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.written.Write(p)
+}
+
+func (t *testTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *testTransport) Resize(cols int, rows int, xpix int, ypix int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sizes = append(t.sizes, [2]int{cols, rows})
+	return nil
+}
+
+func TestStartTransport(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 2)
+	tr := &testTransport{
+		Reader: bytes.NewReader([]byte("ab\x1b[6n")),
+	}
+	closed := make(chan struct{})
+	vt.Attach(func(ev tcell.Event) {
+		if _, ok := ev.(*EventClosed); ok {
+			close(closed)
+		}
+	})
+	assert.NoError(t, vt.StartTransport(tr))
+	<-closed
+
+	assert.Equal(t, "ab  \n    ", vt.String())
+	vt.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+	vt.Resize(6, 3)
+	vt.Close()
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	assert.Equal(t, "\x1b[1;3Rx", tr.written.String())
+	assert.Equal(t, [][2]int{{4, 2}, {6, 3}}, tr.sizes)
+	assert.True(t, tr.closed)
+}
@@ -9,9 +9,8 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
-	"syscall"
+	"time"
 
-	"github.com/creack/pty"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
 )
@@ -30,12 +29,54 @@ type VT struct {
 	// Set the TERM environment variable to be passed to the command's
 	// environment. If not set, xterm-256color will be used
 	TERM string
+	// Scrollback is the maximum number of lines kept in the history of the
+	// primary screen. Set to 0 to disable history
+	Scrollback int
+	// WordDelimiters are the runes which separate words when selecting in
+	// SelectWord mode
+	WordDelimiters string
+	// HighlightStyle is the style used to draw matches passed to Highlight
+	HighlightStyle tcell.Style
+	// ReplyWriter, if set, receives everything the terminal sends to the
+	// application: replies to queries such as DA and DSR, and encoded key,
+	// paste and mouse events. If not set, they are written to the transport
+	// the terminal was started with, or discarded if there is none
+	ReplyWriter io.Writer
+	// FrameRate is the maximum number of EventRedraws posted per second.
+	// Changes to the screen are coalesced: an EventRedraw is posted when
+	// the output of the application goes idle, or once MaxLatency has
+	// passed since the screen changed. Set to 0 to post an EventRedraw as
+	// soon as the screen changes after a Draw
+	FrameRate int
+	// MaxLatency is the longest a change to the screen waits for an
+	// EventRedraw when FrameRate is set
+	MaxLatency time.Duration
+	// FormatOtherKeys sets the encoding of keys when the application
+	// enables xterm's modifyOtherKeys. If set, keys are sent as
+	// CSI code ; modifiers u. Otherwise they are sent as
+	// CSI 27 ; modifiers ; code ~
+	FormatOtherKeys bool
 
 	mu sync.Mutex
 
 	activeScreen  [][]cell
 	altScreen     [][]cell
 	primaryScreen [][]cell
+	// history holds lines scrolled off the top of the primary screen,
+	// oldest first
+	history [][]cell
+	// viewOffset is the number of lines the view is scrolled back into
+	// history
+	viewOffset int
+	selection  selection
+	// highlights are the highlighted matches, and highlightRows indexes
+	// them by each row they cover
+	highlights    []Match
+	highlightRows map[int][]Match
+	// damaged marks the rows of the active screen which changed since the
+	// last Draw. If allDamaged is set, the whole view is redrawn
+	damaged    []bool
+	allDamaged bool
 
 	charsets charsets
 	cursor   cursor
@@ -49,15 +90,54 @@ type VT struct {
 	primaryState cursorState
 	altState     cursorState
 
-	cmd          *exec.Cmd
 	dirty        bool
 	eventHandler func(tcell.Event)
 	parser       *Parser
-	pty          *os.File
+	transport    Transport
 	surface      Surface
 	events       chan tcell.Event
+	// redraw signals the goroutine reading from the application to flush
+	// a pending redraw. redrawPosted is set once an EventRedraw has been
+	// delivered for the current changes, until they are drawn
+	redraw       chan struct{}
+	redrawPosted bool
+	lastRedraw   time.Time
+	latencyTimer *time.Timer
+	frameTimer   *time.Timer
+	// frozen is the frame drawn during a synchronized update. syncChanged
+	// is set if the screen changed during the update
+	frozen      *frame
+	syncChanged bool
+	syncGen     int
+	syncTimer   *time.Timer
+	// input parses bytes passed to Write. writeMu serializes calls to
+	// Write
+	input   *Parser
+	writeMu sync.Mutex
 
 	mouseBtn tcell.ButtonMask
+	// cellWidth and cellHeight are the size of a cell in pixels, or 0 if
+	// unknown
+	cellWidth  int
+	cellHeight int
+	// primaryKeyFlags and altKeyFlags are the stacks of kitty keyboard
+	// protocol flags of the primary and alternate screens
+	primaryKeyFlags []int
+	altKeyFlags     []int
+	// modifyOtherKeys is the level of xterm's modifyOtherKeys set by the
+	// application
+	modifyOtherKeys int
+	// vt52Graphics is set while the graphics character set of VT52 mode is
+	// selected. vt52Args collects the position of a VT52 direct cursor
+	// address, and is nil if none is expected
+	vt52Graphics bool
+	vt52Args     []rune
+	// flash is set while a visual bell is shown. flashGen identifies the
+	// latest bell
+	flash    bool
+	flashGen int
+	// focused is set while the host has focus, as reported by SetFocused
+	focused bool
 }
 
 type cursorState struct {
@@ -80,8 +160,13 @@ func New() *VT {
 		tabs = append(tabs, column(i))
 	}
 	return &VT{
-		Logger: log.New(io.Discard, "", log.Flags()),
-		OSC8:   true,
+		Logger:         log.New(io.Discard, "", log.Flags()),
+		OSC8:           true,
+		Scrollback:     defaultScrollback,
+		WordDelimiters: defaultWordDelimiters,
+		HighlightStyle: defaultHighlightStyle,
+		FrameRate:      defaultFrameRate,
+		MaxLatency:     defaultMaxLatency,
 		charsets: charsets{
 			designations: map[charsetDesignator]charset{
 				g0: ascii,
@@ -114,22 +199,23 @@ func New() *VT {
 			decawm: true,
 		},
 		tabStop:      tabs,
+		focused:      true,
 		eventHandler: func(ev tcell.Event) { return },
 		// Buffering to 2 events. If there is ever a case where one
 		// sequence can trigger two events, this should be increased
 		events: make(chan tcell.Event, 2),
+		redraw: make(chan struct{}, 1),
 	}
 }
 
-// Start starts the terminal with the specified command. Start returns when the
-// command has been successfully started.
+// Start starts the terminal with the specified command, running in a local
+// pty. Start returns when the command has been successfully started.
 func (vt *VT) Start(cmd *exec.Cmd) error {
 	if cmd == nil {
 		return fmt.Errorf("no command to run")
 	}
-	vt.cmd = cmd
 	vt.mu.Lock()
-	w, h := vt.surface.Size()
+	w, h := vt.startSize()
 	vt.mu.Unlock()
 
 	if vt.TERM == "" {
@@ -143,51 +229,143 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 	cmd.Env = append(env, "TERM="+vt.TERM)
 
 	// Start the command with a pty.
-	var err error
-	winsize := pty.Winsize{
-		Cols: uint16(w),
-		Rows: uint16(h),
-	}
-	vt.pty, err = pty.StartWithAttrs(
-		cmd,
-		&winsize,
-		&syscall.SysProcAttr{
-			Setsid:  true,
-			Setctty: true,
-			Ctty:    1,
-		})
+	t, err := startPty(cmd, w, h)
 	if err != nil {
 		return err
 	}
+	return vt.StartTransport(t)
+}
+
+// StartTransport starts the terminal connected to an application through t.
+// Output read from t is drawn, and keys, mouse events and replies are written
+// to it. The terminal is sized to its Surface, and t is resized along with the
+// terminal. The transport is closed by Close
+func (vt *VT) StartTransport(t Transport) error {
+	if t == nil {
+		return fmt.Errorf("no transport")
+	}
+	vt.mu.Lock()
+	vt.transport = t
+	w, h := vt.startSize()
+	vt.mu.Unlock()
 
 	vt.Resize(w, h)
-	vt.parser = NewParser(vt.pty)
-	go func() {
-		defer vt.recover()
-		for {
+	vt.mu.Lock()
+	vt.parser = NewParser(t)
+	vt.mu.Unlock()
+	go vt.run()
+	return nil
+}
+
+// run applies the output of the application to the terminal and delivers
+// events, until the output ends
+func (vt *VT) run() {
+	defer vt.recover()
+	for {
+		var seq Sequence
+		select {
+		case ev := <-vt.events:
+			vt.eventHandler(ev)
+			continue
+		case <-vt.redraw:
+			vt.flushRedraw(true)
+			continue
+		case seq = <-vt.parser.sequences:
+		default:
+			// Nothing has been parsed: the output of the
+			// application is idle
+			vt.flushRedraw(true)
 			select {
 			case ev := <-vt.events:
 				vt.eventHandler(ev)
-			default:
-				seq := vt.parser.Next()
-				switch seq := seq.(type) {
-				case EOF:
-					vt.eventHandler(&EventClosed{
-						EventTerminal: newEventTerminal(vt),
-					})
-					return
-				default:
-					vt.update(seq)
-				}
+				continue
+			case <-vt.redraw:
+				vt.flushRedraw(true)
+				continue
+			case seq = <-vt.parser.sequences:
 			}
 		}
-	}()
-	return nil
+		switch seq.(type) {
+		case EOF:
+			vt.eventHandler(&EventClosed{
+				EventTerminal: newEventTerminal(vt),
+			})
+			return
+		default:
+			vt.update(seq)
+		}
+	}
+}
+
+// startSize returns the size to start the terminal at: the size of the
+// Surface, or the current size if there is no Surface
+func (vt *VT) startSize() (int, int) {
+	if vt.surface != nil {
+		return vt.surface.Size()
+	}
+	if vt.height() == 0 {
+		return 80, 24
+	}
+	return vt.width(), vt.height()
+}
+
+// Write parses p as output from an application and updates the terminal
+// synchronously. Sequences may be split across calls. A VT which is only
+// written to needs no command, pty or Surface: replies go to the ReplyWriter,
+// and events are delivered to the attached handler before Write returns. When
+// FrameRate is set, a single EventRedraw is delivered at the end of a Write
+// which changed the screen. A VT which has not been resized is sized to 80x24
+// on the first write
+func (vt *VT) Write(p []byte) (int, error) {
+	vt.writeMu.Lock()
+	defer vt.writeMu.Unlock()
+	vt.mu.Lock()
+	if vt.height() == 0 {
+		vt.resize(80, 24)
+	}
+	vt.mu.Unlock()
+	if vt.input == nil {
+		vt.input = newFeedParser(func(seq Sequence) {
+			vt.update(seq)
+			vt.dispatchEvents()
+		})
+	}
+	vt.input.feed(p)
+	vt.flushRedraw(false)
+	return len(p), nil
+}
+
+// dispatchEvents passes pending events to the event handler without waiting
+// for more
+func (vt *VT) dispatchEvents() {
+	for {
+		select {
+		case ev := <-vt.events:
+			vt.eventHandler(ev)
+		default:
+			return
+		}
+	}
+}
+
+// send writes s to the application
+func (vt *VT) send(s string) {
+	switch {
+	case vt.ReplyWriter != nil:
+		_, _ = io.WriteString(vt.ReplyWriter, s)
+	case vt.transport != nil:
+		_, _ = io.WriteString(vt.transport, s)
+	}
 }
 
 func (vt *VT) update(seq Sequence) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
+	if vt.mode&vt52 != 0 {
+		vt.vt52(seq)
+		vt.changed()
+		return
+	}
 	switch seq := seq.(type) {
 	case Print:
 		vt.print(rune(seq))
@@ -198,6 +376,10 @@ func (vt *VT) update(seq Sequence) {
 		vt.esc(string(esc))
 	case CSI:
 		csi := append(seq.Intermediate, seq.Final)
+		if string(csi) == "m" && seq.Subparameters != nil {
+			vt.sgrSubparameters(seq.Subparameters)
+			break
+		}
 		vt.csi(string(csi), seq.Parameters)
 	case OSC:
 		vt.osc(string(seq.Payload))
@@ -205,13 +387,7 @@ func (vt *VT) update(seq Sequence) {
 	case DCSData:
 	case DCSEndOfData:
 	}
-	// TODO optimize when we post EventRedraw
-	if !vt.dirty {
-		vt.dirty = true
-		vt.postEvent(&EventRedraw{
-			EventTerminal: newEventTerminal(vt),
-		})
-	}
+	vt.changed()
 }
 
 func (vt *VT) String() string {
@@ -250,57 +426,112 @@ func (vt *VT) recover() {
 	vt.Close()
 }
 
-// row, col, style, vis
+// row, col, style, vis. The row is relative to the view, and the cursor is not
+// visible when the view is scrolled back far enough to hide it
 func (vt *VT) Cursor() (int, int, tcell.CursorStyle, bool) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
 	vis := vt.mode&dectcem > 0
-	return int(vt.cursor.row), int(vt.cursor.col), vt.cursor.style, vis
+	rw := int(vt.cursor.row) + vt.offset()
+	if rw >= vt.height() {
+		vis = false
+	}
+	return rw, int(vt.cursor.col), vt.cursor.style, vis
 }
 
+// Resize resizes the terminal to w columns and h rows. Soft-wrapped lines of
+// the primary screen are reflowed to the new width, and lines which no longer
+// fit are moved into the scrollback history. The alternate screen is truncated
+// or padded
 func (vt *VT) Resize(w int, h int) {
-	primary := vt.primaryScreen
-	vt.altScreen = make([][]cell, h)
-	vt.primaryScreen = make([][]cell, h)
-	for i := range vt.altScreen {
-		vt.altScreen[i] = make([]cell, w)
-		vt.primaryScreen[i] = make([]cell, w)
-	}
-	last := vt.cursor.row
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.resize(w, h)
+	if vt.transport == nil {
+		return
+	}
+	_ = vt.transport.Resize(w, h, w*vt.cellWidth, h*vt.cellHeight)
+}
+
+// SetCellSize sets the size of a cell of the Surface in pixels. The size is
+// used to report mouse positions in pixels with SGR-Pixels mouse mode, and is
+// passed to the transport along with the size of the terminal
+func (vt *VT) SetCellSize(w int, h int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if w < 0 || h < 0 {
+		return
+	}
+	vt.cellWidth = w
+	vt.cellHeight = h
+	if vt.transport == nil || vt.height() == 0 {
+		return
+	}
+	_ = vt.transport.Resize(vt.width(), vt.height(), vt.width()*w, vt.height()*h)
+}
+
+func (vt *VT) resize(w int, h int) {
+	if w < 1 || h < 1 {
+		return
+	}
+	alt := vt.mode&smcup != 0
+	// The frame of a synchronized update no longer fits
+	vt.endSync()
+	vt.clearSelection()
+	vt.setHighlights(nil)
+
+	// The primary cursor is saved while the alternate screen is active
+	primaryCursor := vt.cursor
+	pending := vt.lastCol
+	if alt {
+		primaryCursor = vt.primaryState.cursor
+		pending = false
+	}
+	if len(vt.primaryScreen) == 0 {
+		vt.primaryScreen = make([][]cell, 1)
+	}
+	screen, history, pos, pending := vt.reflow(w, h, Position{
+		Row: int(primaryCursor.row),
+		Col: int(primaryCursor.col),
+	}, pending)
+	vt.primaryScreen = screen
+	vt.history = history
+	vt.trimHistory()
+	vt.viewOffset = 0
+	vt.altScreen = resizeGrid(vt.altScreen, w, h)
+
+	primaryCursor.row = row(pos.Row)
+	primaryCursor.col = column(pos.Col)
+	vt.margin.top = 0
 	vt.margin.bottom = row(h) - 1
+	vt.margin.left = 0
 	vt.margin.right = column(w) - 1
-	vt.cursor.row = 0
-	vt.cursor.col = 0
-	vt.lastCol = false
-	vt.activeScreen = vt.primaryScreen
-
-	// transfer primary to new, skipping the last row
-	for row := 0; row < len(primary); row += 1 {
-		if row == int(last) {
-			break
-		}
-		wrapped := false
-		for col := 0; col < len(primary[0]); col += 1 {
-			cell := primary[row][col]
-			vt.cursor.attrs = cell.attrs
-			vt.print(cell.content)
-			wrapped = cell.wrapped
-		}
-		if !wrapped {
-			vt.nel()
-		}
-	}
-	switch vt.mode & smcup {
-	case 0:
-		vt.activeScreen = vt.primaryScreen
-	default:
+
+	switch alt {
+	case true:
 		vt.activeScreen = vt.altScreen
+		vt.primaryState.cursor = primaryCursor
+		vt.lastCol = false
+		vt.cursor.row, vt.cursor.col = clampPosition(vt.cursor.row, vt.cursor.col, w, h)
+	case false:
+		vt.activeScreen = vt.primaryScreen
+		vt.cursor = primaryCursor
+		vt.lastCol = pending
+		vt.primaryState.cursor.row, vt.primaryState.cursor.col = clampPosition(vt.primaryState.cursor.row, vt.primaryState.cursor.col, w, h)
 	}
+	vt.altState.cursor.row, vt.altState.cursor.col = clampPosition(vt.altState.cursor.row, vt.altState.cursor.col, w, h)
+	vt.damageAll()
+}
 
-	_ = pty.Setsize(vt.pty, &pty.Winsize{
-		Cols: uint16(w),
-		Rows: uint16(h),
-	})
+// clampPosition limits a position to a screen of size w x h
+func clampPosition(r row, c column, w int, h int) (row, column) {
+	if r > row(h-1) {
+		r = row(h - 1)
+	}
+	if c > column(w-1) {
+		c = column(w - 1)
+	}
+	return r, c
 }
 
 func (vt *VT) width() int {
@@ -317,7 +548,7 @@ func (vt *VT) height() int {
 // print sets the current cell contents to the given rune. The attributes will
 // be copied from the current cursor attributes
 func (vt *VT) print(r rune) {
-	if vt.charsets.designations[vt.charsets.selected] == decSpecialAndLineDrawing {
+	if vt.charsets.designations[vt.charsets.selected] == decSpecialAndLineDrawing || vt.vt52Graphics {
 		shifted, ok := decSpecial[r]
 		if ok {
 			r = shifted
@@ -333,12 +564,14 @@ func (vt *VT) print(r rune) {
 		col := vt.cursor.col
 		rw := vt.cursor.row
 		vt.activeScreen[rw][col].wrapped = true
+		vt.damage(rw)
 		vt.nel()
 	}
 
 	col := vt.cursor.col
 	rw := vt.cursor.row
 	w := runewidth.RuneWidth(r)
+	vt.damage(rw)
 
 	if vt.mode&irm != 0 {
 		line := vt.activeScreen[rw]
@@ -364,6 +597,7 @@ func (vt *VT) print(r rune) {
 		content: r,
 		width:   w,
 		attrs:   vt.cursor.attrs,
+		ext:     vt.cursor.ext,
 	}
 
 	vt.activeScreen[rw][col] = cell
@@ -375,6 +609,7 @@ func (vt *VT) print(r rune) {
 		}
 		vt.activeScreen[rw][col+i].content = ' '
 		vt.activeScreen[rw][col+i].attrs = vt.cursor.attrs
+		vt.activeScreen[rw][col+i].ext = vt.cursor.ext
 	}
 
 	switch {
@@ -388,27 +623,36 @@ func (vt *VT) print(r rune) {
 }
 
 // scrollUp shifts all text upward by n rows. Semantically, this is backwards -
-// usually scroll up would mean you shift rows down
+// usually scroll up would mean you shift rows down. Rows scrolled off the top
+// of the primary screen are saved to the history
 func (vt *VT) scrollUp(n int) {
-	for row := range vt.activeScreen {
-		if row > int(vt.margin.bottom) {
-			continue
-		}
-		if row < int(vt.margin.top) {
-			continue
-		}
-		if row+n > int(vt.margin.bottom) {
+	vt.scrollSelection(int(vt.margin.top), int(vt.margin.bottom), n, vt.recordsHistory())
+	vt.scrollHighlights(int(vt.margin.top), int(vt.margin.bottom), n, vt.recordsHistory())
+	vt.pushHistory(n)
+	vt.damageRows(vt.margin.top, vt.margin.bottom)
+	for r := vt.margin.top; r <= vt.margin.bottom; r += 1 {
+		if r+row(n) > vt.margin.bottom {
 			for col := vt.margin.left; col <= vt.margin.right; col += 1 {
-				vt.activeScreen[row][col].erase(vt.cursor.attrs)
+				vt.activeScreen[r][col].erase(vt.cursor.attrs)
 			}
 			continue
 		}
-		copy(vt.activeScreen[row], vt.activeScreen[row+n])
+		vt.copyRow(r, r+row(n))
 	}
 }
 
+// copyRow copies the cells of row src between the left and right margins to
+// row dst
+func (vt *VT) copyRow(dst row, src row) {
+	left, right := vt.margin.left, vt.margin.right+1
+	copy(vt.activeScreen[dst][left:right], vt.activeScreen[src][left:right])
+}
+
 // scrollDown shifts all lines down by n rows.
 func (vt *VT) scrollDown(n int) {
+	vt.scrollSelection(int(vt.margin.top), int(vt.margin.bottom), -n, false)
+	vt.scrollHighlights(int(vt.margin.top), int(vt.margin.bottom), -n, false)
+	vt.damageRows(vt.margin.top, vt.margin.bottom)
 	for r := vt.margin.bottom; r >= vt.margin.top; r -= 1 {
 		if r-row(n) < vt.margin.top {
 			for col := vt.margin.left; col <= vt.margin.right; col += 1 {
@@ -416,18 +660,16 @@ func (vt *VT) scrollDown(n int) {
 			}
 			continue
 		}
-		copy(vt.activeScreen[r], vt.activeScreen[r-row(n)])
+		vt.copyRow(r, r-row(n))
 	}
 }
 
 func (vt *VT) Close() {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
-	if vt.cmd != nil && vt.cmd.Process != nil {
-		vt.cmd.Process.Kill()
-		vt.cmd.Wait()
+	if vt.transport != nil {
+		vt.transport.Close()
 	}
-	vt.pty.Close()
 }
 
 func (vt *VT) Attach(fn func(ev tcell.Event)) {
@@ -452,24 +694,28 @@ func (vt *VT) SetSurface(srf Surface) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
 	vt.surface = srf
+	vt.damageAll()
 }
 
-func (vt *VT) Draw() {
+// Draw paints the rows of the terminal which have changed since the last Draw
+// onto the Surface. Draw returns the rectangles which were painted. If none
+// were, hosts may skip showing the Surface
+func (vt *VT) Draw() []Rect {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
 	vt.dirty = false
+	vt.redrawPosted = false
 	if vt.surface == nil {
-		return
+		return nil
 	}
-	for row := 0; row < vt.height(); row += 1 {
-		for col := 0; col < vt.width(); {
-			cell := vt.activeScreen[row][col]
-			w := cell.width
-			vt.surface.SetContent(col, row, cell.content, cell.combining, cell.attrs)
-			if w == 0 {
-				w = 1
-			}
-			col += w
+	if vt.frozen != nil {
+		return vt.drawFrozen()
+	}
+	rects := vt.damagedRects()
+	selStart, selEnd := vt.selectionBounds()
+	for _, rect := range rects {
+		for row := rect.Y; row < rect.Y+rect.Height; row += 1 {
+			vt.drawRow(row, selStart, selEnd)
 		}
 	}
 	// for _, s := range buf.getVisibleSixels() {
@@ -480,6 +726,45 @@ func (vt *VT) Draw() {
 	// 	// string terminator(ST)
 	// 	os.Stdout.Write([]byte{0x1b, 0x5c})
 	// }
+	return rects
+}
+
+// drawRow paints row of the view onto the Surface
+func (vt *VT) drawRow(row int, selStart Position, selEnd Position) {
+	line := vt.viewRow(row)
+	for col := 0; col < vt.width(); {
+		var cell cell
+		if col < len(line) {
+			cell = line[col]
+		}
+		w := cell.width
+		style := cell.style()
+		gridRow := row - vt.offset()
+		if vt.highlighted(gridRow, col) {
+			style = vt.HighlightStyle
+		}
+		if vt.selected(gridRow, col, selStart, selEnd) || (w > 1 && vt.selected(gridRow, col+w-1, selStart, selEnd)) {
+			_, _, attrs := style.Decompose()
+			style = style.Reverse(attrs&tcell.AttrReverse == 0)
+		}
+		if vt.reverseVideo() {
+			style = swapDefaultColors(style)
+		}
+		if w == 0 {
+			w = 1
+		}
+		if cell.ext.attrs&ExtAttrConceal != 0 {
+			// Concealed text is drawn blank over every column it
+			// covers, but is still copied
+			for i := 0; i < w && col+i < vt.width(); i += 1 {
+				vt.surface.SetContent(col+i, row, ' ', nil, style)
+			}
+			col += w
+			continue
+		}
+		vt.surface.SetContent(col, row, cell.content, cell.combining, style)
+		col += w
+	}
 }
 
 func (vt *VT) HandleEvent(e tcell.Event) bool {
@@ -487,22 +772,35 @@ func (vt *VT) HandleEvent(e tcell.Event) bool {
 	defer vt.mu.Unlock()
 	switch e := e.(type) {
 	case *tcell.EventKey:
-		vt.pty.WriteString(keyCode(e))
+		// Typing always returns the view to the live screen
+		if vt.viewOffset != 0 {
+			vt.viewOffset = 0
+			vt.damageAll()
+		}
+		if flags := vt.keyboardFlags(); flags != 0 {
+			vt.send(kittyKeyCode(e, flags, vt.mode))
+			return true
+		}
+		if str, ok := otherKeyCode(e, vt.modifyOtherKeys, vt.FormatOtherKeys); ok {
+			vt.send(str)
+			return true
+		}
+		vt.send(keyCode(e, vt.mode))
 		return true
 	case *tcell.EventPaste:
 		switch {
 		case vt.mode&paste == 0:
 			return false
 		case e.Start():
-			vt.pty.WriteString(info.PasteStart)
+			vt.send(info.PasteStart)
 			return true
 		case e.End():
-			vt.pty.WriteString(info.PasteEnd)
+			vt.send(info.PasteEnd)
 			return true
 		}
 	case *tcell.EventMouse:
 		str := vt.handleMouse(e)
-		vt.pty.WriteString(str)
+		vt.send(str)
 	}
 	return false
 }
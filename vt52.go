@@ -0,0 +1,137 @@
+package tcellterm
+
+import "github.com/gdamore/tcell/v2"
+
+// vt52 applies a sequence in VT52 mode, which is entered when DECANM is reset.
+// VT52 escape sequences are ESC followed by a single character. Control
+// sequences and strings of ANSI mode are ignored
+func (vt *VT) vt52(seq Sequence) {
+	switch seq := seq.(type) {
+	case Print:
+		if vt.vt52Args != nil {
+			vt.vt52Arg(rune(seq))
+			return
+		}
+		vt.print(rune(seq))
+	case C0:
+		vt.c0(rune(seq))
+	case ESC:
+		vt.vt52Args = nil
+		vt.vt52Esc(seq.Final)
+	}
+}
+
+func (vt *VT) vt52Esc(final rune) {
+	switch final {
+	case 'A':
+		// Cursor up
+		vt.cuu(1)
+	case 'B':
+		// Cursor down
+		vt.cud(1)
+	case 'C':
+		// Cursor right
+		vt.cuf(1)
+	case 'D':
+		// Cursor left
+		vt.cub(1)
+	case 'F':
+		// Enter graphics mode
+		vt.vt52Graphics = true
+	case 'G':
+		// Exit graphics mode
+		vt.vt52Graphics = false
+	case 'H':
+		// Cursor home
+		vt.lastCol = false
+		vt.cursor.row = 0
+		vt.cursor.col = 0
+	case 'I':
+		// Reverse line feed
+		vt.ri()
+	case 'J':
+		// Erase to end of screen
+		vt.ed(0)
+	case 'K':
+		// Erase to end of line
+		vt.el(0)
+	case 'Y':
+		// Direct cursor address. The line and column follow
+		vt.vt52Args = []rune{}
+	case 'Z':
+		// Identify
+		vt.send("\x1b/Z")
+	case '=':
+		// Enter alternate keypad mode
+		vt.mode |= deckpam
+	case '>':
+		// Exit alternate keypad mode
+		vt.mode &^= deckpam
+	case '<':
+		// Enter ANSI mode
+		vt.mode &^= vt52
+		vt.vt52Graphics = false
+	}
+}
+
+// vt52Arg collects the line and column of a direct cursor address. Both are
+// sent offset by 32. Positions past the edge of the screen move the cursor to
+// the edge
+func (vt *VT) vt52Arg(r rune) {
+	vt.vt52Args = append(vt.vt52Args, r)
+	if len(vt.vt52Args) < 2 {
+		return
+	}
+	rw := row(vt.vt52Args[0] - 32)
+	col := column(vt.vt52Args[1] - 32)
+	vt.vt52Args = nil
+	if rw < 0 {
+		rw = 0
+	}
+	if col < 0 {
+		col = 0
+	}
+	vt.lastCol = false
+	vt.cursor.row, vt.cursor.col = clampPosition(rw, col, vt.width(), vt.height())
+}
+
+// vt52KeyCode returns the encoding of a key in VT52 mode. The keys of the
+// keypad send their digits, or ESC ? sequences in alternate keypad mode
+func vt52KeyCode(k tcell.Key, m mode) (string, bool) {
+	switch k {
+	case tcell.KeyUp:
+		return "\x1bA", true
+	case tcell.KeyDown:
+		return "\x1bB", true
+	case tcell.KeyRight:
+		return "\x1bC", true
+	case tcell.KeyLeft:
+		return "\x1bD", true
+	case tcell.KeyF1:
+		return "\x1bP", true
+	case tcell.KeyF2:
+		return "\x1bQ", true
+	case tcell.KeyF3:
+		return "\x1bR", true
+	case tcell.KeyF4:
+		return "\x1bS", true
+	}
+	keypad := map[tcell.Key]string{
+		tcell.KeyUpLeft:    "7",
+		tcell.KeyUpRight:   "9",
+		tcell.KeyCenter:    "5",
+		tcell.KeyDownLeft:  "1",
+		tcell.KeyDownRight: "3",
+	}
+	if m&deckpam != 0 {
+		keypad = map[tcell.Key]string{
+			tcell.KeyUpLeft:    "\x1b?w",
+			tcell.KeyUpRight:   "\x1b?y",
+			tcell.KeyCenter:    "\x1b?u",
+			tcell.KeyDownLeft:  "\x1b?q",
+			tcell.KeyDownRight: "\x1b?s",
+		}
+	}
+	str, ok := keypad[k]
+	return str, ok
+}
@@ -0,0 +1,204 @@
+package tcellterm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVT52(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		screen string
+		row    int
+		col    int
+	}{
+		{
+			name:   "ANSI sequences are ignored",
+			input:  "\x1b[?2l\x1b[2;2Hab",
+			screen: "ab  \n    \n    ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "cursor movement",
+			input:  "\x1b[?2l\x1bB\x1bC\x1bCa\x1bA\x1bD\x1bDb",
+			screen: " b  \n  a \n    ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "cursor movement stops at the edges",
+			input:  "\x1b[?2l\x1bA\x1bDa",
+			screen: "a   \n    \n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "direct cursor address",
+			input:  "\x1b[?2l\x1bY!\"a",
+			screen: "    \n  a \n    ",
+			row:    1,
+			col:    3,
+		},
+		{
+			name:   "direct cursor address past the edge",
+			input:  "\x1b[?2l\x1bY~~a",
+			screen: "    \n    \n   a",
+			row:    2,
+			col:    3,
+		},
+		{
+			name:   "home",
+			input:  "\x1b[?2lab\r\ncd\x1bHx",
+			screen: "xb  \ncd  \n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "erase to end of screen",
+			input:  "\x1b[?2labcd\r\nefgh\x1bY !\x1bJ",
+			screen: "a   \n    \n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "erase to end of line",
+			input:  "\x1b[?2labcd\r\nefgh\x1bY !\x1bK",
+			screen: "a   \nefgh\n    ",
+			row:    0,
+			col:    1,
+		},
+		{
+			name:   "reverse line feed",
+			input:  "\x1b[?2la\x1bIb",
+			screen: " b  \na   \n    ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "graphics",
+			input:  "\x1b[?2l\x1bFq\x1bGq",
+			screen: "─q  \n    \n    ",
+			row:    0,
+			col:    2,
+		},
+		{
+			name:   "ANSI mode",
+			input:  "\x1b[?2l\x1bF\x1b<\x1b[2;2Hq",
+			screen: "    \n q  \n    ",
+			row:    1,
+			col:    2,
+		},
+		{
+			name:   "ANSI escape sequences are ignored",
+			input:  "\x1b[?2l\x1bc\x1b[2;2Hq",
+			screen: "q   \n    \n    ",
+			row:    0,
+			col:    1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 3)
+			_, _ = vt.Write([]byte(test.input))
+			assert.Equal(t, test.screen, vt.String())
+			row, col, _, _ := vt.Cursor()
+			assert.Equal(t, test.row, row, "row")
+			assert.Equal(t, test.col, col, "col")
+		})
+	}
+}
+
+func TestVT52Replies(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	_, _ = vt.Write([]byte("\x1b[?2$p\x1b[?2l\x1bZ\x1b[c\x1b<\x1b[?2$p"))
+	assert.Equal(t, "\x1b[?2;1$y\x1b/Z\x1b[?2;1$y", buf.String())
+}
+
+func TestVT52KeyCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     mode
+		event    *tcell.EventKey
+		expected string
+	}{
+		{
+			name:     "up",
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+			expected: "\x1bA",
+		},
+		{
+			name:     "left in cursor key mode",
+			mode:     decckm,
+			event:    tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone),
+			expected: "\x1bD",
+		},
+		{
+			name:     "modified right",
+			event:    tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl),
+			expected: "\x1bC",
+		},
+		{
+			name:     "PF1",
+			event:    tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone),
+			expected: "\x1bP",
+		},
+		{
+			name:     "PF4",
+			event:    tcell.NewEventKey(tcell.KeyF4, 0, tcell.ModNone),
+			expected: "\x1bS",
+		},
+		{
+			name:     "keypad",
+			event:    tcell.NewEventKey(tcell.KeyCenter, 0, tcell.ModNone),
+			expected: "5",
+		},
+		{
+			name:     "alternate keypad",
+			mode:     deckpam,
+			event:    tcell.NewEventKey(tcell.KeyUpLeft, 0, tcell.ModNone),
+			expected: "\x1b?w",
+		},
+		{
+			name:     "rune",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			expected: "a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := keyCode(test.event, vt52|test.mode)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestVT52KeypadMode(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	_, _ = vt.Write([]byte("\x1b[?2l\x1b="))
+	vt.HandleEvent(tcell.NewEventKey(tcell.KeyDownRight, 0, tcell.ModNone))
+	_, _ = vt.Write([]byte("\x1b>"))
+	vt.HandleEvent(tcell.NewEventKey(tcell.KeyDownRight, 0, tcell.ModNone))
+	assert.Equal(t, "\x1b?s3", buf.String())
+}
+
+func TestVT52AltScroll(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	buf := &strings.Builder{}
+	vt.ReplyWriter = buf
+	_, _ = vt.Write([]byte("\x1b[?1049h\x1b[?2l"))
+	vt.handleMouse(tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone))
+	assert.Equal(t, "\x1bA\x1bA\x1bA", buf.String())
+}
@@ -1,8 +1,11 @@
 package tcellterm
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/gdamore/tcell/v2"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -146,3 +149,216 @@ func TestCombiningRunes(t *testing.T) {
 
 	assert.Equal(t, "h̷̗ \n  ", vt.String())
 }
+
+func TestScrollback(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	vt.print('a')
+	vt.nel()
+	vt.print('b')
+	vt.nel()
+	vt.print('c')
+	assert.Equal(t, "b \nc ", vt.String())
+	assert.Equal(t, 1, len(vt.history))
+	assert.Equal(t, 'a', vt.history[0][0].content)
+
+	vt.ScrollViewUp(5)
+	assert.Equal(t, 1, vt.ViewOffset())
+	assert.Equal(t, 'a', vt.viewRow(0)[0].content)
+	assert.Equal(t, 'b', vt.viewRow(1)[0].content)
+
+	// New history keeps the view anchored
+	vt.nel()
+	assert.Equal(t, 2, vt.ViewOffset())
+	assert.Equal(t, 'a', vt.viewRow(0)[0].content)
+
+	vt.ScrollViewDown(1)
+	assert.Equal(t, 1, vt.ViewOffset())
+	vt.ScrollViewToBottom()
+	assert.Equal(t, 0, vt.ViewOffset())
+
+	vt.ed(3)
+	assert.Equal(t, 0, len(vt.history))
+
+	t.Run("negative scroll", func(t *testing.T) {
+		vt := New()
+		vt.Resize(2, 2)
+		vt.SetSurface(newTestSurface(2, 2))
+		_, _ = vt.Write([]byte("a\r\nb\r\nc"))
+		vt.ScrollViewUp(-2)
+		assert.Equal(t, 0, vt.ViewOffset())
+		vt.Draw()
+		vt.ScrollViewDown(-5)
+		assert.Equal(t, 1, vt.ViewOffset())
+		vt.Draw()
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		vt := New()
+		vt.Scrollback = 2
+		vt.Resize(1, 1)
+		for _, r := range "abcd" {
+			vt.print(r)
+			vt.nel()
+		}
+		assert.Equal(t, 2, len(vt.history))
+		assert.Equal(t, 'c', vt.history[0][0].content)
+	})
+
+	t.Run("alt screen", func(t *testing.T) {
+		vt := New()
+		vt.Resize(1, 1)
+		vt.decset([]int{1049})
+		vt.print('a')
+		vt.nel()
+		assert.Equal(t, 0, len(vt.history))
+	})
+
+	t.Run("scroll region", func(t *testing.T) {
+		vt := New()
+		vt.Resize(1, 3)
+		vt.decstbm([]int{2, 3})
+		vt.scrollUp(1)
+		assert.Equal(t, 0, len(vt.history))
+	})
+}
+
+func TestResizeReflow(t *testing.T) {
+	t.Run("grow", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		for _, r := range "abcdef" {
+			vt.print(r)
+		}
+		assert.Equal(t, "abcd\nef  ", vt.String())
+		vt.Resize(8, 2)
+		assert.Equal(t, "abcdef  \n        ", vt.String())
+		assert.Equal(t, row(0), vt.cursor.row)
+		assert.Equal(t, column(6), vt.cursor.col)
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		vt := New()
+		vt.Resize(6, 2)
+		for _, r := range "abcdef" {
+			vt.print(r)
+		}
+		assert.True(t, vt.lastCol)
+		vt.Resize(3, 2)
+		assert.Equal(t, "abc\ndef", vt.String())
+		assert.Equal(t, row(1), vt.cursor.row)
+		assert.Equal(t, column(2), vt.cursor.col)
+		assert.True(t, vt.lastCol)
+		vt.print('g')
+		assert.Equal(t, "def\ng  ", vt.String())
+		assert.Equal(t, 1, len(vt.history))
+	})
+
+	t.Run("hard newlines", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 3)
+		vt.print('a')
+		vt.nel()
+		vt.print('b')
+		vt.Resize(2, 3)
+		assert.Equal(t, "a \nb \n  ", vt.String())
+		assert.Equal(t, row(1), vt.cursor.row)
+		assert.Equal(t, column(1), vt.cursor.col)
+	})
+
+	t.Run("overflow into history", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		for _, r := range "abcdefg" {
+			vt.print(r)
+		}
+		vt.Resize(2, 2)
+		assert.Equal(t, "ef\ng ", vt.String())
+		assert.Equal(t, 2, len(vt.history))
+		assert.Equal(t, 'a', vt.history[0][0].content)
+
+		// Growing pulls the history back onto the screen
+		vt.Resize(4, 2)
+		assert.Equal(t, "abcd\nefg ", vt.String())
+		assert.Equal(t, 0, len(vt.history))
+	})
+
+	t.Run("wide characters", func(t *testing.T) {
+		vt := New()
+		vt.Resize(3, 2)
+		vt.print('a')
+		vt.print('つ')
+		vt.Resize(2, 2)
+		assert.Equal(t, 'a', vt.activeScreen[0][0].content)
+		assert.Equal(t, 'つ', vt.activeScreen[1][0].content)
+	})
+
+	t.Run("pending wrap after wide characters", func(t *testing.T) {
+		vt := New()
+		vt.Resize(5, 3)
+		_, _ = vt.Write([]byte("ab世界x"))
+		vt.Resize(3, 3)
+		assert.Equal(t, "ab \n世  \n界 x", vt.String())
+		assert.Equal(t, row(2), vt.cursor.row)
+		assert.Equal(t, column(2), vt.cursor.col)
+		assert.True(t, vt.lastCol)
+	})
+
+	t.Run("alt screen", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		vt.print('a')
+		vt.decset([]int{1049})
+		vt.cursor.col = 0
+		vt.print('b')
+		vt.print('c')
+		vt.Resize(2, 3)
+		assert.Equal(t, "bc\n  \n  ", vt.String())
+		vt.decrst([]int{1049})
+		assert.Equal(t, "a \n  \n  ", vt.String())
+		assert.Equal(t, column(1), vt.cursor.col)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("default size", func(t *testing.T) {
+		vt := New()
+		n, err := vt.Write([]byte("hello"))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, n)
+		assert.Equal(t, 80, vt.width())
+		assert.Equal(t, 24, vt.height())
+	})
+
+	t.Run("split sequences", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		input := []byte("a\x1b[2;3Hつ")
+		for i := range input {
+			_, _ = vt.Write(input[i : i+1])
+		}
+		assert.Equal(t, "a   \n  つ ", vt.String())
+	})
+
+	t.Run("replies", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		buf := &bytes.Buffer{}
+		vt.ReplyWriter = buf
+		_, _ = vt.Write([]byte("\x1b[2;3H\x1b[6n"))
+		assert.Equal(t, "\x1b[2;3R", buf.String())
+	})
+
+	t.Run("events", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		titles := []string{}
+		vt.Attach(func(ev tcell.Event) {
+			if ev, ok := ev.(*EventTitle); ok {
+				titles = append(titles, ev.Title())
+			}
+		})
+		_, _ = vt.Write([]byte("\x07\x1b]0;one\x07\x07\x1b]2;two\x07"))
+		assert.Equal(t, []string{"one", "two"}, titles)
+	})
+}